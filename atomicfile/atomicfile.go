@@ -0,0 +1,61 @@
+package atomicfile
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile atomically replaces the contents of filename with data.
+// It behaves like ioutil.WriteFile, except that readers never observe a partially written file.
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return Write(filename, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Write atomically replaces the contents of filename with everything written by the write function.
+// The contents are written to a temporary file in the same directory, synced to disk and then renamed over filename,
+// so a crash or failed write leaves the original file intact. If filename already exists its mode is preserved,
+// otherwise the new file is created with perm. Symlinks are followed so that the file they point to is replaced.
+func Write(filename string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	if resolved, linkErr := filepath.EvalSymlinks(filename); linkErr == nil {
+		filename = resolved
+	}
+	if info, statErr := os.Stat(filename); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
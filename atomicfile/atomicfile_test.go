@@ -0,0 +1,145 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const originalContents = "original contents\n"
+
+func setup(perm os.FileMode) (string, string) {
+	dir, err := ioutil.TempDir("", "ignoreit-atomicfile")
+	if err != nil {
+		panic(err)
+	}
+	filename := filepath.Join(dir, ".gitignore")
+	if err := ioutil.WriteFile(filename, []byte(originalContents), perm); err != nil {
+		panic(err)
+	}
+	if err := os.Chmod(filename, perm); err != nil {
+		panic(err)
+	}
+	return dir, filename
+}
+
+func assertDirContents(t *testing.T, dir string, expected int) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+	if len(files) != expected {
+		t.Errorf("Directory should contain %d file(s), got %d instead", expected, len(files))
+	}
+}
+
+func TestWriteFileReplacesContents(t *testing.T) {
+	dir, filename := setup(0644)
+	defer os.RemoveAll(dir)
+
+	if err := WriteFile(filename, []byte("new contents\n"), 0644); err != nil {
+		t.Errorf("Error should not be returned: %s", err)
+	}
+
+	contents, _ := ioutil.ReadFile(filename)
+	if string(contents) != "new contents\n" {
+		t.Errorf("File should contain the new contents, got %q instead", contents)
+	}
+	assertDirContents(t, dir, 1)
+}
+
+func TestWriteFileCreatesWithPerm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-atomicfile")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".ignoreit.yml")
+
+	if err := WriteFile(filename, []byte("schema_version: 1\n"), 0640); err != nil {
+		t.Errorf("Error should not be returned: %s", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("File should exist: %s", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("File mode should be 0640, got %o instead", info.Mode().Perm())
+	}
+}
+
+func TestWritePreservesMode(t *testing.T) {
+	dir, filename := setup(0600)
+	defer os.RemoveAll(dir)
+
+	if err := WriteFile(filename, []byte("new contents\n"), 0644); err != nil {
+		t.Errorf("Error should not be returned: %s", err)
+	}
+
+	info, _ := os.Stat(filename)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("File mode should be preserved as 0600, got %o instead", info.Mode().Perm())
+	}
+}
+
+func TestWriteFailureKeepsOriginal(t *testing.T) {
+	dir, filename := setup(0644)
+	defer os.RemoveAll(dir)
+
+	diskFull := errors.New("no space left on device")
+	err := Write(filename, 0644, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "partial"); err != nil {
+			return err
+		}
+		return diskFull
+	})
+	if err != diskFull {
+		t.Errorf("Write error should be returned, got %v instead", err)
+	}
+
+	contents, _ := ioutil.ReadFile(filename)
+	if string(contents) != originalContents {
+		t.Errorf("Original file should survive intact, got %q instead", contents)
+	}
+	assertDirContents(t, dir, 1)
+}
+
+func TestWriteMissingDirectory(t *testing.T) {
+	dir, filename := setup(0644)
+	defer os.RemoveAll(dir)
+
+	if err := WriteFile(filepath.Join(dir, "missing", ".gitignore"), []byte{}, 0644); err == nil {
+		t.Error("Error should be returned when the directory does not exist")
+	}
+
+	contents, _ := ioutil.ReadFile(filename)
+	if string(contents) != originalContents {
+		t.Errorf("Unrelated file should be untouched, got %q instead", contents)
+	}
+}
+
+func TestWriteFollowsSymlinks(t *testing.T) {
+	dir, filename := setup(0644)
+	defer os.RemoveAll(dir)
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filename, link); err != nil {
+		t.Skipf("Symlinks are not supported: %s", err)
+	}
+
+	if err := WriteFile(link, []byte("through link\n"), 0644); err != nil {
+		t.Errorf("Error should not be returned: %s", err)
+	}
+
+	if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Symlink should not be replaced by a regular file")
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	if string(contents) != "through link\n" {
+		t.Errorf("Symlink target should contain the new contents, got %q instead", contents)
+	}
+}
@@ -0,0 +1,95 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/whoshuu/ignoreit/walk"
+)
+
+// maxEvidence limits how many matching files are recorded for each detected entry.
+const maxEvidence = 3
+
+// signal associates the file name patterns that identify a kind of project with the github/gitignore entry for it.
+// Patterns are matched against base names with filepath.Match.
+type signal struct {
+	entry    string
+	patterns []string
+}
+
+var signals = []signal{
+	{"C", []string{"*.c"}},
+	{"C++", []string{"*.cpp", "*.cc", "*.cxx", "*.hpp"}},
+	{"CMake", []string{"CMakeLists.txt"}},
+	{"Composer", []string{"composer.json"}},
+	{"Dart", []string{"pubspec.yaml"}},
+	{"Elixir", []string{"mix.exs"}},
+	{"Go", []string{"go.mod", "*.go"}},
+	{"Gradle", []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}},
+	{"Haskell", []string{"*.cabal", "stack.yaml"}},
+	{"Java", []string{"*.java", "pom.xml"}},
+	{"Maven", []string{"pom.xml"}},
+	{"Node", []string{"package.json"}},
+	{"Python", []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile", "*.py"}},
+	{"Ruby", []string{"Gemfile", "*.gemspec"}},
+	{"Rust", []string{"Cargo.toml"}},
+	{"Scala", []string{"build.sbt", "*.scala"}},
+	{"Swift", []string{"Package.swift"}},
+	{"Terraform", []string{"*.tf", "*.tfvars"}},
+	{"VisualStudio", []string{"*.sln", "*.csproj", "*.fsproj", "*.vbproj", "*.vcxproj"}},
+}
+
+// Match is a github/gitignore entry detected for a project, along with a few of the files that suggested it.
+type Match struct {
+	Entry    string
+	Evidence []string
+}
+
+// Detect walks the tree rooted at root looking for files that identify the languages and tools of the project.
+// Directories skipped by walk.Skip are never walked.
+// Matches are returned in alphabetical order of their entries, with evidence paths relative to root.
+func Detect(root string) ([]Match, error) {
+	evidence := make(map[string][]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && walk.Skip(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		for _, s := range signals {
+			if len(evidence[s.entry]) < maxEvidence && matchesAny(info.Name(), s.patterns) {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				evidence[s.entry] = append(evidence[s.entry], filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, s := range signals {
+		if files, ok := evidence[s.entry]; ok {
+			matches = append(matches, Match{s.entry, files})
+		}
+	}
+	return matches, nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
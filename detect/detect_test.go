@@ -0,0 +1,52 @@
+package detect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	root, err := ioutil.TempDir("", "ignoreit-detect")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := []string{"go.mod", "main.go", "infra/main.tf", "node_modules/left-pad/package.json", "web/package.json"}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	matches, err := Detect(root)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := []Match{
+		{"Go", []string{"go.mod", "main.go"}},
+		{"Node", []string{"web/package.json"}},
+		{"Terraform", []string{"infra/main.tf"}},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Should have detected %v, got %v instead", expected, matches)
+	}
+	for i := range expected {
+		if matches[i].Entry != expected[i].Entry || len(matches[i].Evidence) != len(expected[i].Evidence) {
+			t.Errorf("Match %d should be %v, got %v instead", i, expected[i], matches[i])
+			continue
+		}
+		for j := range expected[i].Evidence {
+			if matches[i].Evidence[j] != expected[i].Evidence[j] {
+				t.Errorf("Match %d should have evidence %v, got %v instead", i, expected[i].Evidence, matches[i].Evidence)
+			}
+		}
+	}
+}
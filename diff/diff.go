@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Op is the kind of change a line of a diff represents.
+type Op int
+
+// Lines of a diff are either kept, deleted from the old text or inserted into the new text.
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+var prefixes = map[Op]string{Equal: " ", Delete: "-", Insert: "+"}
+
+// Line is a single line of a diff, without its line terminator.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines computes a minimal line diff turning the text before a change into the text after it, based on their longest common subsequence.
+// A missing newline at the end of either text is not reported as a change.
+func Lines(before, after string) []Line {
+	a, b := split(before), split(after)
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, Line{Equal, a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, Line{Delete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// Changed reports whether the diff has any deleted or inserted lines.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteUnified writes the changes of the diff in unified format, with up to context unchanged lines around each of them.
+// Changes that are close enough to share context are written in the same hunk.
+func WriteUnified(w io.Writer, lines []Line, context int) error {
+	for start := 0; start < len(lines); {
+		first := nextChange(lines, start)
+		if first == len(lines) {
+			break
+		}
+
+		// Extend the hunk while the next change is within reach of the context of the last one.
+		last := first
+		for next := nextChange(lines, last+1); next < len(lines) && next-last <= 2*context+1; next = nextChange(lines, last+1) {
+			last = next
+		}
+
+		from, to := max(first-context, start), min(last+context+1, len(lines))
+		oldStart, newStart := position(lines[:from])
+		oldCount, newCount := position(lines[from:to])
+		if _, err := fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldStart+1, oldCount, newStart+1, newCount); err != nil {
+			return err
+		}
+		for _, line := range lines[from:to] {
+			if _, err := fmt.Fprintln(w, prefixes[line.Op]+line.Text); err != nil {
+				return err
+			}
+		}
+		start = to
+	}
+	return nil
+}
+
+// split breaks text into lines, without treating a final line terminator as the start of an empty line.
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func nextChange(lines []Line, from int) int {
+	for from < len(lines) && lines[from].Op == Equal {
+		from++
+	}
+	return from
+}
+
+// position counts the lines of the old and new texts covered by the diff lines.
+func position(lines []Line) (int, int) {
+	before, after := 0, 0
+	for _, line := range lines {
+		if line.Op != Insert {
+			before++
+		}
+		if line.Op != Delete {
+			after++
+		}
+	}
+	return before, after
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
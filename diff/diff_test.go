@@ -0,0 +1,39 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	lines := Lines("a\nb\nc\n", "a\nc\nd")
+
+	expected := []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "d"}}
+	if len(lines) != len(expected) {
+		t.Fatalf("Diff should be %v, got %v instead", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d should be %v, got %v instead", i, expected[i], lines[i])
+		}
+	}
+
+	if Changed(Lines("a\nb\n", "a\nb")) {
+		t.Errorf("A missing final newline should not be a change")
+	}
+}
+
+func TestWriteUnified(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	after := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+
+	var buffer bytes.Buffer
+	if err := WriteUnified(&buffer, Lines(before, after), 1); err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n@@ -10,1 +10,2 @@\n 10\n+11\n"
+	if buffer.String() != expected {
+		t.Errorf("Unified diff should be %q, got %q instead", expected, buffer.String())
+	}
+}
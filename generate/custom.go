@@ -0,0 +1,28 @@
+package generate
+
+import (
+	"strings"
+
+	"github.com/whoshuu/ignoreit/pattern"
+)
+
+// Covering returns the pattern of the sections that already decides whether the path named by a custom pattern is ignored,
+// or nil when none of them does. A pattern with wildcards names no single path, so it is only decided by an identical
+// pattern or its negation. Literal patterns are checked as a path at the root of the project.
+func Covering(sections []Section, custom *pattern.Pattern) *pattern.Pattern {
+	matcher := Matcher(sections)
+	body := strings.TrimPrefix(custom.Text, "!")
+
+	if strings.ContainsAny(body, "*?[\\") {
+		for i := len(matcher) - 1; i >= 0; i-- {
+			if strings.TrimPrefix(matcher[i].Text, "!") == body {
+				return matcher[i]
+			}
+		}
+		return nil
+	}
+
+	path := strings.TrimPrefix(strings.TrimSuffix(body, "/"), "/")
+	p, _ := matcher.Match(path, custom.DirOnly)
+	return p
+}
@@ -0,0 +1,40 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/whoshuu/ignoreit/pattern"
+)
+
+func TestCovering(t *testing.T) {
+	sections := []Section{
+		{Source: "github/gitignore - master", Entry: "Go", Contents: "*.exe\n*.test\nvendor/\n"},
+		{Source: "github/gitignore - master", Entry: "Node", Contents: "logs\n*.log\n!keep.log\n"},
+	}
+	tests := []struct {
+		custom, covering string
+	}{
+		{"app.exe", "*.exe"},
+		{"/debug.log", "*.log"},
+		{"keep.log", "!keep.log"},
+		{"vendor/", "vendor/"},
+		{"*.test", "*.test"},
+		{"!*.log", "*.log"},
+		{"*.tmp", ""},
+		{"/dist/", ""},
+	}
+	for _, test := range tests {
+		custom, err := pattern.Parse(test.custom)
+		if err != nil {
+			t.Fatalf("Pattern %s should parse, got %v instead", test.custom, err)
+		}
+
+		covering := ""
+		if p := Covering(sections, custom); p != nil {
+			covering = p.Text
+		}
+		if covering != test.covering {
+			t.Errorf("Pattern covering %s should be %q, got %q instead", test.custom, test.covering, covering)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/whoshuu/ignoreit/pattern"
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+// Format describes the syntax of an ignore file that can be generated from gitignore patterns.
+// Filename is the conventional name of the file, used to infer the format of an output and in the generated header.
+// Formats sharing gitignore semantics have no translate function and are written verbatim.
+// Formats with several pattern syntaxes report the syntax of each translated pattern,
+// and a directive line is emitted whenever it changes.
+type Format struct {
+	Name      string
+	Filename  string
+	translate func(p *pattern.Pattern) (string, error)
+	syntax    func(p *pattern.Pattern) string
+}
+
+var formats = map[string]Format{
+	"gitignore":    {Name: "gitignore", Filename: ".gitignore"},
+	"npmignore":    {Name: "npmignore", Filename: ".npmignore"},
+	"gcloudignore": {Name: "gcloudignore", Filename: ".gcloudignore"},
+	"dockerignore": {Name: "dockerignore", Filename: ".dockerignore", translate: translateDocker},
+	"helmignore":   {Name: "helmignore", Filename: ".helmignore", translate: translateHelm},
+	"hgignore":     {Name: "hgignore", Filename: ".hgignore", translate: translateHg, syntax: hgSyntax},
+}
+
+// Formats returns the names of every supported output format in sorted order.
+func Formats() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatFor returns the format of the output.
+// If the output does not name a format, it is inferred from the file name of its path, falling back to gitignore.
+func FormatFor(output spec.Output) (Format, error) {
+	if output.Format != "" {
+		format, ok := formats[output.Format]
+		if !ok {
+			return Format{}, fmt.Errorf("unknown format %q for output %s, expected one of: %s", output.Format, output.Path, strings.Join(Formats(), ", "))
+		}
+		return format, nil
+	}
+
+	for _, format := range formats {
+		if filepath.Base(output.Path) == format.Filename {
+			return format, nil
+		}
+	}
+	return formats["gitignore"], nil
+}
+
+// Verbatim reports whether the format shares gitignore semantics, so that sections are written to it unchanged.
+func (format Format) Verbatim() bool {
+	return format.translate == nil
+}
+
+// Translate converts the gitignore contents of a section into the syntax of the format.
+// Blank lines and comments are kept as is. Patterns that cannot be represented are replaced by a comment
+// explaining why, and the same explanation is returned as a warning.
+func (format Format) Translate(contents string) (string, []string) {
+	if format.translate == nil {
+		return contents, nil
+	}
+
+	var warnings []string
+	var syntax string
+	lines := strings.SplitAfter(contents, "\n")
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\r\n")
+		p, err := pattern.Parse(text)
+		if err == nil && p == nil {
+			continue
+		}
+
+		var translated string
+		if err == nil {
+			translated, err = format.translate(p)
+		}
+		if err != nil {
+			warning := fmt.Sprintf("cannot translate %q to %s: %s", text, format.Name, err)
+			warnings = append(warnings, warning)
+			translated = "# ignoreit: " + warning
+		} else if format.syntax != nil && format.syntax(p) != syntax {
+			syntax = format.syntax(p)
+			translated = "syntax: " + syntax + "\n" + translated
+		}
+		lines[i] = translated + line[len(text):]
+	}
+	return strings.Join(lines, ""), warnings
+}
+
+// translateDocker roots a gitignore pattern at the build context the way .dockerignore expects.
+// Docker matches every pattern against the full path, so patterns git applies at any depth are prefixed with **/.
+// Docker has no directory-only rules, but excluding a directory excludes its contents, so trailing slashes are dropped.
+// As a result, a directory-only pattern like build/ also excludes a file named build, which git would not ignore.
+// Docker negates character classes with [^...] as Go's filepath.Match does, rather than with [!...].
+func translateDocker(p *pattern.Pattern) (string, error) {
+	body := strings.TrimPrefix(p.Text, "!")
+	body = strings.TrimSuffix(body, "/")
+	body = negateClasses(body)
+	if p.Anchored {
+		body = strings.TrimPrefix(body, "/")
+	} else if !strings.HasPrefix(body, "**/") {
+		body = "**/" + body
+	}
+
+	if p.Negate {
+		return "!" + body, nil
+	}
+	return body, nil
+}
+
+// negateClasses rewrites the negated character classes of a pattern from [!...] to [^...], leaving escaped brackets alone.
+func negateClasses(body string) string {
+	out := []byte(body)
+	inClass := false
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '\\':
+			i++
+		case !inClass && out[i] == '[':
+			inClass = true
+			if i+1 < len(out) && out[i+1] == '!' {
+				out[i+1] = '^'
+				i++
+			}
+			// A ] right after the opening bracket is part of the class rather than closing it.
+			if i+1 < len(out) && out[i+1] == ']' {
+				i++
+			}
+		case inClass && out[i] == ']':
+			inClass = false
+		}
+	}
+	return string(out)
+}
+
+// translateHelm keeps patterns that .helmignore understands, which is gitignore syntax without ** support.
+// Helm matches with Go's filepath.Match as Docker does, so negated character classes are rewritten the same way.
+func translateHelm(p *pattern.Pattern) (string, error) {
+	if strings.Contains(p.Text, "**") {
+		return "", fmt.Errorf("** is not supported")
+	}
+	return negateClasses(p.Text), nil
+}
+
+// hgSyntax picks glob syntax for patterns Mercurial globs match the same way git does,
+// which are the unanchored file patterns, and regexp syntax for everything else.
+func hgSyntax(p *pattern.Pattern) string {
+	if p.Anchored || p.DirOnly || strings.ContainsAny(p.Text, "\\{}") || strings.Contains(p.Text, "**") {
+		return "regexp"
+	}
+	return "glob"
+}
+
+// translateHg converts a gitignore pattern to .hgignore syntax.
+// Mercurial searches regexps against the path of every file and directory, so the anchored expression of the pattern
+// is used directly, and directory-only patterns are rewritten to match paths inside the directory instead.
+// Mercurial has no way to re-include an ignored path, so negations are rejected.
+func translateHg(p *pattern.Pattern) (string, error) {
+	if p.Negate {
+		return "", fmt.Errorf("negated patterns cannot be represented")
+	}
+	if hgSyntax(p) == "glob" {
+		return escapeHash(p.Text), nil
+	}
+	if p.DirOnly {
+		return escapeHash(strings.TrimSuffix(p.Regexp(), "$")) + "/", nil
+	}
+	return escapeHash(p.Regexp()), nil
+}
+
+// escapeHash escapes every # of a translated pattern, since Mercurial starts a comment at any unescaped # of a line
+// and unescapes \# before matching, in glob and regexp syntax alike.
+func escapeHash(line string) string {
+	var out bytes.Buffer
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			out.WriteByte(line[i])
+			if i+1 < len(line) {
+				i++
+				out.WriteByte(line[i])
+			}
+		case '#':
+			out.WriteString("\\#")
+		default:
+			out.WriteByte(line[i])
+		}
+	}
+	return out.String()
+}
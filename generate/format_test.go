@@ -0,0 +1,156 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/whoshuu/ignoreit/pattern"
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+const templateContents = `# Binaries
+*.exe
+/bin/
+vendor/
+!keep.exe
+docs/**/*.md
+`
+
+func TestFormatForInfersFromPath(t *testing.T) {
+	cases := map[string]string{
+		".gitignore":           "gitignore",
+		"app/.dockerignore":    "dockerignore",
+		"charts/.helmignore":   "helmignore",
+		".npmignore":           "npmignore",
+		"deploy/.gcloudignore": "gcloudignore",
+		"ignore.txt":           "gitignore",
+	}
+
+	for path, expected := range cases {
+		format, err := FormatFor(spec.Output{Path: path})
+		if err != nil {
+			t.Errorf("Error should not be returned for %s: %s", path, err)
+		}
+		if format.Name != expected {
+			t.Errorf("Format for %s should be %s, got %s instead", path, expected, format.Name)
+		}
+	}
+}
+
+func TestFormatForUnknown(t *testing.T) {
+	if _, err := FormatFor(spec.Output{Path: "ignore", Format: "svnignore"}); err == nil {
+		t.Error("Error should be returned for unknown format")
+	}
+}
+
+func TestTranslateDocker(t *testing.T) {
+	format, _ := FormatFor(spec.Output{Path: ".dockerignore"})
+	contents, warnings := format.Translate(templateContents)
+
+	expected := `# Binaries
+**/*.exe
+bin
+**/vendor
+!**/keep.exe
+docs/**/*.md
+`
+	if contents != expected {
+		t.Errorf("Translated contents should be:\n%s\ngot:\n%s", expected, contents)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("No warnings should be returned, got %v instead", warnings)
+	}
+}
+
+func TestTranslateDockerClasses(t *testing.T) {
+	tests := []struct{ text, expected string }{
+		{"*.py[!co]", "**/*.py[^co]"},
+		{"/build[!]x]/", "build[^]x]"},
+		{"log[0-9!]", "**/log[0-9!]"},
+		{"\\[!literal]", "**/\\[!literal]"},
+		{"!keep[!a].txt", "!**/keep[^a].txt"},
+	}
+	for _, test := range tests {
+		p, err := pattern.Parse(test.text)
+		if err != nil {
+			t.Fatalf("Pattern %q should parse, got %v instead", test.text, err)
+		}
+		if translated, _ := translateDocker(p); translated != test.expected {
+			t.Errorf("Pattern %q should translate to %q, got %q instead", test.text, test.expected, translated)
+		}
+	}
+}
+
+func TestTranslateHgHash(t *testing.T) {
+	tests := []struct{ text, expected string }{
+		{"\\#*\\#", "^(?:.*/)?\\#[^/]*\\#$"},
+		{"foo#bar", "foo\\#bar"},
+		{"/notes#/", "^notes\\#/"},
+	}
+	for _, test := range tests {
+		p, err := pattern.Parse(test.text)
+		if err != nil {
+			t.Fatalf("Pattern %q should parse, got %v instead", test.text, err)
+		}
+		if translated, _ := translateHg(p); translated != test.expected {
+			t.Errorf("Pattern %q should translate to %q, got %q instead", test.text, test.expected, translated)
+		}
+	}
+}
+
+func TestTranslateHelm(t *testing.T) {
+	format, _ := FormatFor(spec.Output{Path: ".helmignore"})
+	contents, warnings := format.Translate(templateContents)
+
+	expected := `# Binaries
+*.exe
+/bin/
+vendor/
+!keep.exe
+# ignoreit: cannot translate "docs/**/*.md" to helmignore: ** is not supported
+`
+	if contents != expected {
+		t.Errorf("Translated contents should be:\n%s\ngot:\n%s", expected, contents)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("One warning should be returned, got %v instead", warnings)
+	}
+}
+
+func TestTranslateHelmClasses(t *testing.T) {
+	tests := []struct{ text, expected string }{
+		{"data[!0-9].bin", "data[^0-9].bin"},
+		{"/build[!]x]/", "/build[^]x]/"},
+		{"\\[!literal]", "\\[!literal]"},
+		{"!keep[!a].txt", "!keep[^a].txt"},
+	}
+	for _, test := range tests {
+		p, err := pattern.Parse(test.text)
+		if err != nil {
+			t.Fatalf("Pattern %q should parse, got %v instead", test.text, err)
+		}
+		if translated, _ := translateHelm(p); translated != test.expected {
+			t.Errorf("Pattern %q should translate to %q, got %q instead", test.text, test.expected, translated)
+		}
+	}
+}
+
+func TestTranslateHg(t *testing.T) {
+	format, _ := FormatFor(spec.Output{Path: ".hgignore"})
+	contents, warnings := format.Translate(templateContents)
+
+	expected := `# Binaries
+syntax: glob
+*.exe
+syntax: regexp
+^bin/
+^(?:.*/)?vendor/
+# ignoreit: cannot translate "!keep.exe" to hgignore: negated patterns cannot be represented
+^docs/(?:.*/)?[^/]*\.md$
+`
+	if contents != expected {
+		t.Errorf("Translated contents should be:\n%s\ngot:\n%s", expected, contents)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("One warning should be returned, got %v instead", warnings)
+	}
+}
@@ -0,0 +1,167 @@
+package generate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+// Version is the version of ignoreit recorded in the header of generated files.
+var Version = "dev"
+
+// Prefixes of the header lines recording the inputs a file was generated from.
+const (
+	versionPrefix  = "# ignoreit: version "
+	specHashPrefix = "# ignoreit: spec sha256:"
+	entryPrefix    = "# ignoreit: entry "
+)
+
+// Markers delimiting the generated lines of exclude files that also hold patterns written by hand,
+// such as .git/info/exclude and the global excludes file.
+const (
+	beginMarker = "# >>> ignoreit begin >>>"
+	endMarker   = "# <<< ignoreit end <<<"
+)
+
+// Provenance records where the contents of an entry section came from.
+// Ref is the commit the contents were fetched at, or the configured ref when it could not be resolved,
+// and Hash is the hex encoded SHA-256 of the contents after applying the entry options.
+type Provenance struct {
+	Source string
+	Entry  string
+	Ref    string
+	Hash   string
+}
+
+// Header is the metadata recorded at the top of a generated file.
+type Header struct {
+	Version  string
+	SpecHash string
+	Entries  []Provenance
+}
+
+// SpecHash returns the hex encoded SHA-256 of the spec, which changes whenever anything the generated file depends on does.
+// The spec is hashed as it would be saved, so that saving it again, which sorts and dedupes its entries, keeps its hash.
+// The spec should already be expanded, so that changes to included specs are reflected as well.
+func SpecHash(config spec.Config) (string, error) {
+	data, err := yaml.Marshal(config.Cleaned())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// contentHash returns the hex encoded SHA-256 of the contents of a section.
+func contentHash(contents string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(contents)))
+}
+
+// header renders the metadata lines written after the title of a generated file.
+// Entries whose contents could not be fetched are left out, just like their sections.
+func header(specHash string, sections []Section) []string {
+	lines := []string{
+		fmt.Sprintln(versionPrefix + Version),
+		fmt.Sprintln(specHashPrefix + specHash),
+	}
+	for _, section := range sections {
+		if section.Entry != "" && section.Contents != "" {
+			lines = append(lines, fmt.Sprintf("%s%s ref=%s sha256=%s source=%s\n",
+				entryPrefix, section.Entry, section.Ref, contentHash(section.Contents), section.Source))
+		}
+	}
+	return lines
+}
+
+// ReadHeader parses the metadata recorded at the top of a generated file, or at the top of its marked lines.
+// Files written by hand or by older versions of ignoreit have an empty header.
+func ReadHeader(contents string) Header {
+	contents = generatedLines(contents)
+	var h Header
+	for _, line := range strings.SplitAfter(contents, "\n") {
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, versionPrefix):
+			h.Version = strings.TrimPrefix(line, versionPrefix)
+		case strings.HasPrefix(line, specHashPrefix):
+			h.SpecHash = strings.TrimPrefix(line, specHashPrefix)
+		case strings.HasPrefix(line, entryPrefix):
+			if provenance, ok := parseProvenance(strings.TrimPrefix(line, entryPrefix)); ok {
+				h.Entries = append(h.Entries, provenance)
+			}
+		}
+	}
+	return h
+}
+
+// parseProvenance parses the fields of an entry line, which are ordered so that the entry and source may contain spaces.
+func parseProvenance(fields string) (Provenance, bool) {
+	ref := strings.Index(fields, " ref=")
+	hash := strings.Index(fields, " sha256=")
+	source := strings.Index(fields, " source=")
+	if ref < 0 || hash < ref || source < hash {
+		return Provenance{}, false
+	}
+	return Provenance{
+		Entry:  fields[:ref],
+		Ref:    fields[ref+len(" ref=") : hash],
+		Hash:   fields[hash+len(" sha256=") : source],
+		Source: fields[source+len(" source="):],
+	}, true
+}
+
+// RecordedSpecHash returns the spec hash recorded in the header of a generated file.
+// Files written by hand or by older versions of ignoreit have no recorded hash.
+func RecordedSpecHash(contents string) (string, bool) {
+	h := ReadHeader(contents)
+	return h.SpecHash, h.SpecHash != ""
+}
+
+// markedRegion returns the offsets of the first byte of the begin marker line and of the byte following the end marker line.
+func markedRegion(contents string) (int, int, bool) {
+	begin := markerLine(contents, beginMarker, 0)
+	if begin < 0 {
+		return 0, 0, false
+	}
+	end := markerLine(contents, endMarker, begin)
+	if end < 0 {
+		return 0, 0, false
+	}
+	if newline := strings.Index(contents[end:], "\n"); newline >= 0 {
+		return begin, end + newline + 1, true
+	}
+	return begin, len(contents), true
+}
+
+// markerLine returns the offset of the first line from offset on that consists of the marker, or -1 if there is none.
+func markerLine(contents, marker string, offset int) int {
+	for offset < len(contents) {
+		line := contents[offset:]
+		if newline := strings.Index(line, "\n"); newline >= 0 {
+			line = line[:newline+1]
+		}
+		if strings.TrimRight(line, "\r\n") == marker {
+			return offset
+		}
+		offset += len(line)
+	}
+	return -1
+}
+
+// generatedLines returns the lines between the markers of the contents, or all of the contents when there are no markers.
+func generatedLines(contents string) string {
+	begin, end, ok := markedRegion(contents)
+	if !ok {
+		return contents
+	}
+	region := contents[begin:end]
+	region = region[strings.Index(region, "\n")+1:]
+	return region[:markerLine(region, endMarker, 0)]
+}
@@ -0,0 +1,78 @@
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+func TestSpecHash(t *testing.T) {
+	config := spec.Config{Custom: []string{".env"}, SchemaVersion: 2}
+	hash, err := SpecHash(config)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	again, _ := SpecHash(spec.Config{Custom: []string{".env"}, SchemaVersion: 2})
+	if hash != again {
+		t.Errorf("Hash of an identical spec should be %s, got %s instead", hash, again)
+	}
+	changed, _ := SpecHash(spec.Config{Custom: []string{".env", "/bin/"}, SchemaVersion: 2})
+	if hash == changed {
+		t.Errorf("Hash of a changed spec should differ from %s", hash)
+	}
+
+	unsorted := spec.Config{Sources: spec.Sources{{Provider: "github", Repo: "github/gitignore", Ref: "master", Entries: []spec.Entry{{Name: "Go"}, {Name: "C"}, {Name: "Go"}}}}}
+	sorted := spec.Config{Sources: spec.Sources{{Provider: "github", Repo: "github/gitignore", Ref: "master", Entries: []spec.Entry{{Name: "C"}, {Name: "Go"}}}}}
+	unsortedHash, _ := SpecHash(unsorted)
+	sortedHash, _ := SpecHash(sorted)
+	if unsortedHash != sortedHash {
+		t.Errorf("Hash of a spec should not change once it is saved, got %s and %s instead", unsortedHash, sortedHash)
+	}
+	if unsorted.Sources[0].Entries[0].Name != "Go" || len(unsorted.Sources[0].Entries) != 3 {
+		t.Errorf("Hashing should leave the spec untouched, got %v instead", unsorted.Sources[0].Entries)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-header")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := spec.Config{Custom: []string{".env"}, SchemaVersion: 2}
+	filename := filepath.Join(dir, ".gitignore")
+	sections := []Section{
+		{Source: "github/gitignore - master", Entry: "Global/Visual Studio", Ref: "d0b3b5b6e8a5d1a8a2f5c8e2f6c1b1a0e3c4d5f6", Contents: "*.suo\n"},
+		{Source: "github/gitignore - master", Entry: "Missing", Ref: "master"},
+		{Contents: ".env\n"},
+	}
+	if _, err = Write(config, sections, spec.Output{Path: filename}); err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	expected, _ := SpecHash(config)
+	if recorded, ok := RecordedSpecHash(string(contents)); !ok || recorded != expected {
+		t.Errorf("Recorded hash should be %s, got %s instead", expected, recorded)
+	}
+	header := ReadHeader(string(contents))
+	if header.Version != Version {
+		t.Errorf("Recorded version should be %s, got %s instead", Version, header.Version)
+	}
+	expectedEntries := []Provenance{{"github/gitignore - master", "Global/Visual Studio", sections[0].Ref, contentHash("*.suo\n")}}
+	if len(header.Entries) != len(expectedEntries) || header.Entries[0] != expectedEntries[0] {
+		t.Errorf("Recorded entries should be %v, got %v instead", expectedEntries, header.Entries)
+	}
+
+	if recorded, ok := RecordedSpecHash("*.exe\n# ignoreit: spec sha256:abc\n"); ok {
+		t.Errorf("Hash after the header should not be recorded, got %s instead", recorded)
+	}
+}
@@ -1,67 +1,316 @@
 package generate
 
 import (
-	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 
+	"github.com/whoshuu/ignoreit/atomicfile"
 	"github.com/whoshuu/ignoreit/network"
 	"github.com/whoshuu/ignoreit/spec"
 )
 
+// Section is a block of patterns in a generated .gitignore file.
+// Entry sections record a description of the Source they were fetched from and the Ref they were fetched at,
+// and custom group sections record the Group name.
+// The section of ungrouped custom patterns has neither an Entry nor a Group.
+// Contents holds the patterns exactly as they will be written to the output file.
+// Missing reports that the entry was not found upstream, rather than failing to be fetched.
+type Section struct {
+	Source   string
+	Entry    string
+	Ref      string
+	Group    string
+	Contents string
+	Missing  bool
+}
+
+// Name returns a human readable label for the section, suitable for grouping reports by entry.
+func (section Section) Name() string {
+	switch {
+	case section.Group != "":
+		return "Custom: " + section.Group
+	case section.Entry == "":
+		return "Custom Patterns"
+	}
+	return fmt.Sprintf("%s (%s)", section.Entry, section.Source)
+}
+
 // Inflate generates a .gitignore file from the input config.
 // Each source specified in the config will be given its own section in the output file.
 // Custom ignore patterns are appended at the end of the file in their own section.
 func Inflate(config spec.Config, ignoreFilename string) error {
-	var generatedLines []string
+	sections, err := Resolve(config)
+	if err != nil {
+		return err
+	}
 
-	generatedLines = append(generatedLines, fmt.Sprintf("#### Auto-generated .gitignore by ignoreit tool (schema version: %d) ####\n", config.SchemaVersion))
+	_, err = Write(config, sections, spec.Output{Path: ignoreFilename})
+	return err
+}
 
+// Resolve fetches the contents of every entry in the config and returns them as sections in output order.
+// Entries are fetched at their configured ref, which is recorded as is, so that generating the same spec from the
+// same upstream contents always produces the same bytes. Pinned entries thus record the commit they are pinned to.
+// Entries whose contents could not be fetched are kept with empty Contents and are left out of the output file,
+// and those that no longer exist upstream are marked as Missing.
+// Custom groups are placed next to the source they reference, or after every source otherwise.
+func Resolve(config spec.Config) ([]Section, error) {
+	sections, _, err := resolve(config, false)
+	return sections, err
+}
+
+// ResolveCommits is like Resolve, but fetches the entries whose ref is a branch or tag at the commit it currently
+// points at, and records that commit. Refs whose commit cannot be looked up, for instance because the provider's API
+// rate limits unauthenticated requests, are fetched as configured and returned as warnings.
+func ResolveCommits(config spec.Config) ([]Section, []string, error) {
+	return resolve(config, true)
+}
+
+func resolve(config spec.Config, commits bool) ([]Section, []string, error) {
+	for _, group := range config.Groups {
+		if err := group.Validate(config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	placed := make(map[string]bool)
+	var sections []Section
+	var warnings []string
 	for _, source := range config.Sources {
-		sourceLines, err := inflatSource(source)
+		for _, group := range config.Groups {
+			if !placed[group.Name] && group.Before != "" && source.Matches(group.Before) {
+				sections = append(sections, groupSection(group))
+				placed[group.Name] = true
+			}
+		}
+
+		sourceSections, sourceWarnings, err := inflatSource(source, commits)
 		if err != nil {
-			return fmt.Errorf("Error inflating source [%s - %s]: %s", source.Repo, source.Branch, err)
+			return nil, nil, fmt.Errorf("Error inflating source [%s]: %s", source, err)
+		}
+		sections = append(sections, sourceSections...)
+		warnings = append(warnings, sourceWarnings...)
+
+		for _, group := range config.Groups {
+			if !placed[group.Name] && group.After != "" && source.Matches(group.After) {
+				sections = append(sections, groupSection(group))
+				placed[group.Name] = true
+			}
+		}
+	}
+
+	for _, group := range config.Groups {
+		if !placed[group.Name] {
+			sections = append(sections, groupSection(group))
 		}
-		generatedLines = append(generatedLines, sourceLines...)
 	}
 
 	if len(config.Custom) > 0 {
-		generatedLines = append(generatedLines, fmt.Sprint("\n### Custom Patterns ###\n\n"))
+		var custom Section
 		for _, pattern := range config.Custom {
-			generatedLines = append(generatedLines, fmt.Sprintln(pattern))
+			custom.Contents += fmt.Sprintln(pattern)
 		}
+		sections = append(sections, custom)
 	}
 
-	return writeToFile(ignoreFilename, generatedLines)
+	return sections, warnings, nil
 }
 
-func inflatSource(source spec.Source) ([]string, error) {
-	var sourceLines []string
-	if len(source.Entries) > 0 {
-		sourceLines = append(sourceLines, fmt.Sprintln("\n### Source:", source.Repo, "-", source.Branch, "###"))
-		for _, entry := range source.Entries {
-			contents := network.EntryContents(source.GetDownloadLink(entry))
-			if contents != "" {
-				sourceLines = append(sourceLines, fmt.Sprintln("\n## Entry:", entry, "##"))
-				sourceLines = append(sourceLines, fmt.Sprint(contents))
+// Write renders the resolved sections of the config to the output, translating patterns into its format.
+// Patterns that the format cannot represent are returned as warnings and left as comments in the output file.
+// Outputs naming a target are shared with patterns written by hand, so only the lines between the markers are replaced.
+// Contents are normalized first, so the same sections always produce the same bytes on every platform.
+// The header records the version of ignoreit, the hash of the config and the provenance of every entry,
+// so that the inputs an output was generated from are known without fetching anything.
+func Write(config spec.Config, sections []Section, output spec.Output) ([]string, error) {
+	format, err := FormatFor(output)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make([]Section, len(sections))
+	for i, section := range sections {
+		section.Contents = normalize(section.Contents)
+		normalized[i] = section
+	}
+	sections = normalized
+
+	var generatedLines []string
+	var warnings []string
+
+	hash, err := SpecHash(config)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedLines = append(generatedLines, fmt.Sprintf("#### Auto-generated %s by ignoreit tool (schema version: %d) ####\n", format.Filename, config.SchemaVersion))
+	generatedLines = append(generatedLines, header(hash, sections)...)
+
+	for i, section := range sections {
+		contents, sectionWarnings := format.Translate(section.Contents)
+		for _, warning := range sectionWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", section.Name(), warning))
+		}
+
+		if section.Group != "" {
+			generatedLines = append(generatedLines, fmt.Sprint("\n### Custom: ", section.Group, " ###\n\n"))
+			generatedLines = append(generatedLines, contents)
+			continue
+		}
+		if section.Entry == "" {
+			generatedLines = append(generatedLines, fmt.Sprint("\n### Custom Patterns ###\n\n"))
+			generatedLines = append(generatedLines, contents)
+			continue
+		}
+
+		if i == 0 || sections[i-1].Source != section.Source {
+			generatedLines = append(generatedLines, fmt.Sprintln("\n### Source:", section.Source, "###"))
+		}
+		if contents != "" {
+			generatedLines = append(generatedLines, fmt.Sprintln("\n## Entry:", section.Entry, "##"))
+			generatedLines = append(generatedLines, fmt.Sprint(contents))
+		}
+	}
+
+	if output.Target != "" {
+		return warnings, writeBetweenMarkers(output.Path, generatedLines)
+	}
+	return warnings, writeToFile(output.Path, generatedLines)
+}
+
+// groupSection renders a custom group as a section, with its description written as comment lines above the patterns.
+func groupSection(group spec.Group) Section {
+	section := Section{Group: group.Name}
+	for _, line := range strings.Split(strings.TrimSpace(group.Description), "\n") {
+		if line != "" {
+			section.Contents += fmt.Sprintln("#", strings.TrimSpace(line))
+		}
+	}
+	for _, pattern := range group.Patterns {
+		section.Contents += fmt.Sprintln(pattern)
+	}
+	return section
+}
+
+// inflatSource fetches every entry of the source at its ref, or at the commit its ref currently points at when
+// commits is set. Refs that cannot be resolved are fetched as configured and reported as warnings, once per ref.
+// Fetched contents are normalized before the entry options are applied.
+func inflatSource(source spec.Source, commits bool) ([]Section, []string, error) {
+	resolved := make(map[string]string)
+	var sections []Section
+	var warnings []string
+	for _, entry := range source.Entries {
+		ref := source.Ref
+		if entry.Ref != "" {
+			ref = entry.Ref
+		}
+		if _, ok := resolved[ref]; !ok {
+			resolved[ref] = ref
+			if commits {
+				if commit, err := source.ResolveRef(ref); err == nil {
+					resolved[ref] = commit
+				} else {
+					warnings = append(warnings, fmt.Sprintf("%s, fetching it as configured", err))
+				}
 			}
 		}
+
+		fetchedEntry := entry
+		fetchedEntry.Ref = resolved[ref]
+		fetched, missing := fetchEntry(source.GetDownloadLink(fetchedEntry))
+		contents, err := applyEntryOptions(entry, normalize(fetched))
+		if err != nil {
+			return nil, nil, err
+		}
+		sections = append(sections, Section{
+			Source:   source.String(),
+			Entry:    entry.Name,
+			Ref:      fetchedEntry.Ref,
+			Contents: contents,
+			Missing:  missing,
+		})
 	}
 
-	return sourceLines, nil
+	return sections, warnings, nil
 }
 
-func writeToFile(filename string, lines []string) error {
-	file, err := os.Create(filename)
+// fetchEntry downloads the contents of an entry, reporting whether the entry does not exist when they are empty.
+// Request errors are reported on stderr, and only a 404 Not Found response means the entry does not exist.
+func fetchEntry(url string) (string, bool) {
+	body, err := network.Fetch(url)
+	if err != nil {
+		status, ok := err.(network.StatusError)
+		if !ok {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return "", ok && status.StatusCode == http.StatusNotFound
+	}
+	return string(body), false
+}
+
+// applyEntryOptions strips the excluded lines from the fetched contents of an entry and adds its appended lines.
+// Excluded lines are replaced by a comment so the generated file shows what was removed.
+// Contents that could not be fetched are left empty, so the entry is still left out of the output file.
+func applyEntryOptions(entry spec.Entry, contents string) (string, error) {
+	if contents == "" || (len(entry.Exclude) == 0 && len(entry.Append) == 0) {
+		return contents, nil
+	}
+
+	excluded, err := entry.Excluder()
 	if err != nil {
+		return "", err
+	}
+
+	lines := strings.SplitAfter(contents, "\n")
+	for i, line := range lines {
+		if text := strings.TrimRight(line, "\r\n"); text != "" && excluded(text) {
+			lines[i] = "# ignoreit: excluded " + text + line[len(text):]
+		}
+	}
+	contents = strings.Join(lines, "")
+
+	if len(entry.Append) > 0 {
+		if !strings.HasSuffix(contents, "\n") {
+			contents += "\n"
+		}
+		contents += "# ignoreit: appended\n"
+		for _, line := range entry.Append {
+			contents += fmt.Sprintln(line)
+		}
+	}
+	return contents, nil
+}
+
+func writeToFile(filename string, lines []string) error {
+	return atomicfile.Write(filename, 0644, func(w io.Writer) error {
+		for _, line := range lines {
+			if _, err := fmt.Fprint(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeBetweenMarkers writes the lines between the begin and end markers of the file, keeping every line outside of them.
+// The marked lines are appended to files that do not have markers yet.
+func writeBetweenMarkers(filename string, lines []string) error {
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	defer file.Close()
 
-	w := bufio.NewWriter(file)
-	for _, line := range lines {
-		fmt.Fprint(w, line)
+	before, after := string(existing), ""
+	if begin, end, ok := markedRegion(before); ok {
+		before, after = before[:begin], before[end:]
+	} else if before != "" && !strings.HasSuffix(before, "\n") {
+		before += "\n"
 	}
 
-	return w.Flush()
+	marked := append([]string{before, fmt.Sprintln(beginMarker)}, lines...)
+	return writeToFile(filename, append(marked, fmt.Sprintln(endMarker), after))
 }
@@ -0,0 +1,252 @@
+package generate
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+func TestApplyEntryOptions(t *testing.T) {
+	entry := spec.Entry{
+		Name:    "Go",
+		Exclude: []string{"vendor/", "re:^\\*\\.(so|dylib)$"},
+		Append:  []string{"/bin/"},
+	}
+	contents := "*.exe\n*.so\n*.dylib\n\n# Dependency directories\nvendor/\n*.test"
+
+	actual, err := applyEntryOptions(entry, contents)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := `*.exe
+# ignoreit: excluded *.so
+# ignoreit: excluded *.dylib
+
+# Dependency directories
+# ignoreit: excluded vendor/
+*.test
+# ignoreit: appended
+/bin/
+`
+	if actual != expected {
+		t.Errorf("Contents should be:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestApplyEntryOptionsMissingContents(t *testing.T) {
+	entry := spec.Entry{Name: "Go", Append: []string{"/bin/"}}
+
+	actual, err := applyEntryOptions(entry, "")
+	if err != nil || actual != "" {
+		t.Errorf("Missing contents should stay empty, got %q and %v instead", actual, err)
+	}
+}
+
+func TestApplyEntryOptionsInvalidRegexp(t *testing.T) {
+	entry := spec.Entry{Name: "Go", Exclude: []string{"re:("}}
+
+	if _, err := applyEntryOptions(entry, "*.exe\n"); err == nil {
+		t.Error("Error should be returned for an invalid exclude regexp")
+	}
+}
+
+func TestResolveGroups(t *testing.T) {
+	config := spec.Config{
+		Sources: spec.Sources{
+			{Provider: "github", Repo: "github/gitignore", Ref: "master"},
+			{Provider: "github", Repo: "whoshuu/gitignore", Ref: "develop"},
+		},
+		Custom: []string{".custompattern"},
+		Groups: []spec.Group{
+			{Name: "Fixtures", Description: "Written by the integration suite.\nSafe to delete.", Patterns: []string{"/tmp-fixtures/"}},
+			{Name: "Early", Patterns: []string{"/early/"}, Before: "github/gitignore@master"},
+			{Name: "Late", Patterns: []string{"/late/"}, After: "github:github/gitignore"},
+		},
+	}
+
+	sections, err := Resolve(config)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := []Section{
+		{Group: "Early", Contents: "/early/\n"},
+		{Group: "Late", Contents: "/late/\n"},
+		{Group: "Fixtures", Contents: "# Written by the integration suite.\n# Safe to delete.\n/tmp-fixtures/\n"},
+		{Contents: ".custompattern\n"},
+	}
+	if len(sections) != len(expected) {
+		t.Fatalf("Should have resolved %d sections, got %d instead", len(expected), len(sections))
+	}
+	for i := range expected {
+		if sections[i] != expected[i] {
+			t.Errorf("Section %d should be %v, got %v instead", i, expected[i], sections[i])
+		}
+	}
+}
+
+func TestResolveGroupUnknownSource(t *testing.T) {
+	config := spec.Config{
+		Groups: []spec.Group{{Name: "Orphan", Patterns: []string{"/orphan/"}, After: "github/gitignore"}},
+	}
+
+	if _, err := Resolve(config); err == nil {
+		t.Error("Error should be returned for a group placed next to an unknown source")
+	}
+}
+
+// stubTransport serves canned bodies by URL in place of the providers, and responds 404 Not Found to anything else.
+// Every requested URL is recorded.
+type stubTransport struct {
+	bodies    map[string]string
+	statuses  map[string]int
+	requested []string
+}
+
+func (stub *stubTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	url := request.URL.String()
+	stub.requested = append(stub.requested, url)
+	status, body := http.StatusNotFound, ""
+	if contents, ok := stub.bodies[url]; ok {
+		status, body = http.StatusOK, contents
+	}
+	if code, ok := stub.statuses[url]; ok {
+		status = code
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    request,
+	}, nil
+}
+
+// stubProviders replaces the HTTP transport with stub until the returned function is called.
+func stubProviders(stub *stubTransport) func() {
+	transport := http.DefaultTransport
+	http.DefaultTransport = stub
+	return func() {
+		http.DefaultTransport = transport
+	}
+}
+
+func TestResolveRefs(t *testing.T) {
+	const (
+		commit  = "4488915eec0b3a45b5c63ead28f286819c0917de"
+		pinned  = "0123456789abcdef0123456789abcdef01234567"
+		commits = "https://api.github.com/repos/github/gitignore/commits/master"
+	)
+	stub := &stubTransport{
+		bodies: map[string]string{
+			"https://raw.githubusercontent.com/github/gitignore/master/Go.gitignore":         "*.exe\n",
+			"https://raw.githubusercontent.com/github/gitignore/" + commit + "/Go.gitignore": "*.exe\n*.test\n",
+			"https://raw.githubusercontent.com/github/gitignore/" + pinned + "/C.gitignore":  "*.o\n",
+		},
+		statuses: map[string]int{commits: http.StatusForbidden},
+	}
+	defer stubProviders(stub)()
+
+	config := spec.Config{Sources: spec.Sources{{Provider: "github", Repo: "github/gitignore", Ref: "master", Entries: []spec.Entry{
+		{Name: "Go"},
+		{Name: "C", Ref: pinned},
+	}}}}
+
+	sections, err := Resolve(config)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	if len(sections) != 2 || sections[0].Ref != "master" || sections[0].Contents != "*.exe\n" || sections[1].Ref != pinned {
+		t.Errorf("Entries should be fetched at their configured ref, got %+v instead", sections)
+	}
+	for _, url := range stub.requested {
+		if url == commits {
+			t.Errorf("Refs should not be resolved without asking for commits")
+		}
+	}
+
+	sections, warnings, err := ResolveCommits(config)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "403") {
+		t.Errorf("A rate limited lookup should be reported as a single warning, got %v instead", warnings)
+	}
+	if sections[0].Ref != "master" || sections[0].Contents != "*.exe\n" {
+		t.Errorf("An unresolved ref should be fetched as configured, got %+v instead", sections[0])
+	}
+
+	stub.bodies[commits] = `{"sha": "` + commit + `"}`
+	delete(stub.statuses, commits)
+	sections, warnings, err = ResolveCommits(config)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("Resolving should succeed without warnings, got %v and %v instead", err, warnings)
+	}
+	if sections[0].Ref != commit || sections[0].Contents != "*.exe\n*.test\n" || sections[1].Ref != pinned {
+		t.Errorf("Entries should be fetched at the commit their ref points at, got %+v instead", sections)
+	}
+}
+
+func TestResolveDuplicateGroups(t *testing.T) {
+	config := spec.Config{
+		Groups: []spec.Group{
+			{Name: "Fixtures", Patterns: []string{"/tmp-fixtures/"}},
+			{Name: "Fixtures", Patterns: []string{"/more-fixtures/"}},
+		},
+	}
+
+	if _, err := Resolve(config); err == nil || !strings.Contains(err.Error(), "Fixtures") {
+		t.Errorf("Error should be returned for groups sharing a name, got %v instead", err)
+	}
+}
+
+func TestWriteBetweenMarkers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-markers")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "exclude")
+	if err = ioutil.WriteFile(filename, []byte("# Written by hand\n*.swp"), 0644); err != nil {
+		panic(err)
+	}
+	output := spec.Output{Path: filename, Target: spec.TargetInfoExclude}
+	config := spec.Config{SchemaVersion: 2}
+	sections := []Section{{Source: "github/gitignore - master", Entry: "Go", Ref: "master", Contents: "*.exe\n"}}
+	if _, err = Write(config, sections, output); err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	// Lines added by hand after the markers are kept as well when writing again.
+	contents, _ := ioutil.ReadFile(filename)
+	if err = ioutil.WriteFile(filename, append(contents, "/build/\n"...), 0644); err != nil {
+		panic(err)
+	}
+	sections[0].Contents = "*.exe\n*.test\n"
+	if _, err = Write(config, sections, output); err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	contents, _ = ioutil.ReadFile(filename)
+	actual := string(contents)
+	if !strings.HasPrefix(actual, "# Written by hand\n*.swp\n"+beginMarker+"\n") {
+		t.Errorf("Lines before the markers should be kept, got %q instead", actual)
+	}
+	if !strings.HasSuffix(actual, "\n"+endMarker+"\n/build/\n") {
+		t.Errorf("Lines after the markers should be kept, got %q instead", actual)
+	}
+	if strings.Count(actual, beginMarker) != 1 || strings.Contains(actual, "*.exe\n\n## Entry") {
+		t.Errorf("Marked lines should be replaced, got %q instead", actual)
+	}
+
+	entries := ParseEntries(actual)
+	if len(entries) != 1 || entries[0].Contents != "*.exe\n*.test\n" || entries[0].Ref != "master" {
+		t.Errorf("Entries should be read from the marked lines, got %+v instead", entries)
+	}
+}
@@ -0,0 +1,28 @@
+package generate
+
+import (
+	"strings"
+
+	"github.com/whoshuu/ignoreit/pattern"
+)
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF, which some editors write at the start of files.
+const byteOrderMark = "\xef\xbb\xbf"
+
+// normalize makes contents byte-stable regardless of how upstream stored them: the byte order mark is stripped,
+// CRLF and CR line endings become LF, trailing spaces are trimmed unless escaped with a backslash, since git
+// ignores them anyway, and non-empty contents always end with a newline so they never run into the next header.
+func normalize(contents string) string {
+	contents = strings.TrimPrefix(contents, byteOrderMark)
+	contents = strings.Replace(contents, "\r\n", "\n", -1)
+	contents = strings.Replace(contents, "\r", "\n", -1)
+	if contents == "" {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = pattern.TrimTrailingSpace(line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,94 @@
+package generate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/golden"
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+// edgeCaseSections exercise the ways upstream templates are stored inconsistently.
+var edgeCaseSections = []Section{
+	{Source: "github/gitignore - master", Entry: "CRLF", Ref: "master", Contents: "# Windows line endings\r\n*.exe\r\n*.dll\r\n"},
+	{Source: "github/gitignore - master", Entry: "NoFinalNewline", Ref: "master", Contents: "\xef\xbb\xbf# Starts with a byte order mark\n*.log"},
+	{Source: "github/gitignore - master", Entry: "Whitespace", Ref: "master", Contents: "*.tmp   \ntrailing\\ \n   \n\tindented\t\nold-mac\rendings\r"},
+	{Group: "Fixtures", Contents: "# Scratch data\n/fixtures/  \n"},
+	{Contents: ".env"},
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		"\xef\xbb\xbf":            "",
+		"a\r\nb":                  "a\nb\n",
+		"a\rb\r":                  "a\nb\n",
+		"a  \n\n":                 "a\n\n",
+		"a\\ \nb\\\\ \n":          "a\\ \nb\\\\\n",
+		"\xef\xbb\xbf*.log\t\r\n": "*.log\t\n",
+	}
+	for contents, expected := range cases {
+		if actual := normalize(contents); actual != expected {
+			t.Errorf("Normalized %q should be %q, got %q instead", contents, expected, actual)
+		}
+		if again := normalize(expected); again != expected {
+			t.Errorf("Normalizing %q again should not change it, got %q instead", expected, again)
+		}
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-golden")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := spec.Config{SchemaVersion: 2}
+	for _, name := range []string{"edge-cases.gitignore", "edge-cases.dockerignore"} {
+		format := filepath.Ext(name)[1:]
+		filename := filepath.Join(dir, name)
+		if _, err := Write(config, edgeCaseSections, spec.Output{Path: filename, Format: format}); err != nil {
+			t.Fatalf("Error should not be returned: %s", err)
+		}
+		actual, err := ioutil.ReadFile(filename)
+		if err != nil {
+			panic(err)
+		}
+
+		golden.Check(t, filepath.Join("testdata", name), actual)
+	}
+}
+
+func TestWriteIsByteStable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-stable")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unix := []Section{{Source: "github/gitignore - master", Entry: "Go", Contents: "*.exe\n*.test\n"}}
+	windows := []Section{{Source: "github/gitignore - master", Entry: "Go", Contents: "\xef\xbb\xbf*.exe  \r\n*.test"}}
+
+	var outputs []string
+	for i, sections := range [][]Section{unix, windows, unix} {
+		filename := filepath.Join(dir, fmt.Sprintf("%d.gitignore", i))
+		if _, err := Write(spec.Config{SchemaVersion: 2}, sections, spec.Output{Path: filename}); err != nil {
+			t.Fatalf("Error should not be returned: %s", err)
+		}
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			panic(err)
+		}
+		outputs = append(outputs, string(contents))
+	}
+
+	for i := 1; i < len(outputs); i++ {
+		if outputs[i] != outputs[0] {
+			t.Errorf("Output %d should be identical to:\n%s\ngot:\n%s", i, outputs[0], outputs[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package generate
+
+import (
+	"strings"
+)
+
+// ParseEntries reads the entry sections back from the contents of a generated file, in the order they were written.
+// Sections are delimited by the headers written by Write, and the blank line separating a section from the
+// next header is not part of its contents. Custom pattern sections are not returned.
+// The ref of each section is read from the provenance recorded in the header of the file, if any.
+// Only the lines between the markers are read from files that have them.
+func ParseEntries(contents string) []Section {
+	contents = generatedLines(contents)
+	var sections []Section
+	var source string
+	var lines []string
+	inEntry := false
+	end := func() {
+		if inEntry {
+			if len(lines) > 0 && lines[len(lines)-1] == "\n" {
+				lines = lines[:len(lines)-1]
+			}
+			sections[len(sections)-1].Contents = strings.Join(lines, "")
+		}
+		inEntry = false
+		lines = nil
+	}
+
+	for _, line := range strings.SplitAfter(contents, "\n") {
+		text := strings.TrimRight(line, "\r\n")
+		if name, ok := sectionHeader(text, "### Source: ", " ###"); ok {
+			end()
+			source = name
+		} else if name, ok := sectionHeader(text, "## Entry: ", " ##"); ok {
+			end()
+			sections = append(sections, Section{Source: source, Entry: name})
+			inEntry = true
+		} else if _, ok := sectionHeader(text, "### Custom", " ###"); ok {
+			end()
+		} else if inEntry {
+			lines = append(lines, line)
+		}
+	}
+	end()
+
+	for _, provenance := range ReadHeader(contents).Entries {
+		for i := range sections {
+			if sections[i].Source == provenance.Source && sections[i].Entry == provenance.Entry {
+				sections[i].Ref = provenance.Ref
+			}
+		}
+	}
+	return sections
+}
+
+// sectionHeader returns the name within a section header line written by Write, if the line starts with prefix and ends with suffix.
+func sectionHeader(text, prefix, suffix string) (string, bool) {
+	if len(text) < len(prefix)+len(suffix) || !strings.HasPrefix(text, prefix) || !strings.HasSuffix(text, suffix) {
+		return "", false
+	}
+	return text[len(prefix) : len(text)-len(suffix)], true
+}
@@ -0,0 +1,47 @@
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+func TestParseEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-parse")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sections := []Section{
+		{Source: "github/gitignore - master", Entry: "Go", Contents: "*.exe\n\n## Test binaries\n*.test\n"},
+		{Source: "github/gitignore - master", Entry: "Empty"},
+		{Source: "gitlab:team/templates - v1", Entry: "Python", Contents: "__pycache__/"},
+		{Group: "Fixtures", Contents: "/fixtures/\n"},
+		{Contents: ".custom\n"},
+	}
+	filename := filepath.Join(dir, ".gitignore")
+	if _, err := Write(spec.Config{SchemaVersion: 2}, sections, spec.Output{Path: filename}); err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := ParseEntries(string(contents))
+
+	expected := []Section{sections[0], sections[2]}
+	expected[1].Contents += "\n"
+	if len(parsed) != len(expected) {
+		t.Fatalf("Parsed entries should be %v, got %v instead", expected, parsed)
+	}
+	for i := range expected {
+		if parsed[i] != expected[i] {
+			t.Errorf("Entry %d should be %+v, got %+v instead", i, expected[i], parsed[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package generate
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/whoshuu/ignoreit/network"
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+// maxRenameCandidates limits how many similarly named entries are fetched to compare their contents.
+const maxRenameCandidates = 5
+
+// Rename is an entry of a source that is likely the new name of an entry no longer found upstream.
+// SameContents reports whether its contents match those previously generated for the old entry,
+// otherwise it was only found by its similar name.
+type Rename struct {
+	Entry        string
+	SameContents bool
+}
+
+type renames []Rename
+
+func (r renames) Len() int {
+	return len(r)
+}
+
+func (r renames) Less(i, j int) bool {
+	if r[i].SameContents != r[j].SameContents {
+		return r[i].SameContents
+	}
+	return r[i].Entry < r[j].Entry
+}
+
+func (r renames) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// FindRenames searches the listing of the source for the likely new names of entry, which is no longer found upstream,
+// such as the same template moved into another directory. previous holds the contents last generated for entry,
+// and candidates whose contents match them after applying the entry options are listed first.
+// Candidates with the same base name as entry are closer matches than those whose name only differs in case or
+// punctuation, so they are ranked first before at most maxRenameCandidates of them are fetched.
+// When none of them has the previous contents, the other entries of the listing are fetched in turn until one does,
+// since a template can also be renamed to something else entirely, like Global/OSX to Global/macOS.
+func FindRenames(source spec.Source, entry spec.Entry, previous string) ([]Rename, error) {
+	names, err := source.ListEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates, others []string
+	for _, name := range names {
+		switch {
+		case name == entry.Name || source.GetEntry(name) != nil:
+		case similarName(entry.Name, name):
+			candidates = append(candidates, name)
+		default:
+			others = append(others, name)
+		}
+	}
+	sort.Stable(byBaseName{candidates, path.Base(entry.Name)})
+	if len(candidates) > maxRenameCandidates {
+		candidates = candidates[:maxRenameCandidates]
+	}
+
+	sameContents := func(name string) (bool, error) {
+		candidate := entry
+		candidate.Name = name
+		contents, err := applyEntryOptions(entry, normalize(network.EntryContents(source.GetDownloadLink(candidate))))
+		if err != nil {
+			return false, err
+		}
+		return previous != "" && contents == normalize(previous), nil
+	}
+
+	var found renames
+	same := false
+	for _, name := range candidates {
+		rename := Rename{Entry: name}
+		if rename.SameContents, err = sameContents(name); err != nil {
+			return nil, err
+		}
+		same = same || rename.SameContents
+		found = append(found, rename)
+	}
+	for i := 0; previous != "" && !same && i < len(others); i++ {
+		if same, err = sameContents(others[i]); err != nil {
+			return nil, err
+		}
+		if same {
+			found = append(found, Rename{Entry: others[i], SameContents: true})
+		}
+	}
+	sort.Stable(found)
+	return found, nil
+}
+
+// byBaseName orders entry names with the same base name as an entry before the others.
+type byBaseName struct {
+	names []string
+	base  string
+}
+
+func (b byBaseName) Len() int {
+	return len(b.names)
+}
+
+func (b byBaseName) Less(i, j int) bool {
+	return path.Base(b.names[i]) == b.base && path.Base(b.names[j]) != b.base
+}
+
+func (b byBaseName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+}
+
+// similarName reports whether two entry names likely refer to the same template, comparing their base names
+// regardless of case and punctuation.
+func similarName(name, other string) bool {
+	a := nameKey(name)
+	return a != "" && a == nameKey(other)
+}
+
+func nameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, path.Base(name))
+}
@@ -0,0 +1,115 @@
+package generate
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+func TestSimilarName(t *testing.T) {
+	tests := []struct {
+		name, other string
+		similar     bool
+	}{
+		{"Global/Eclipse", "community/Eclipse", true},
+		{"VisualStudioCode", "Global/visual-studio-code", true},
+		{"Go", "Golang", false},
+		{"Java", "JavaScript", false},
+		{"Python", "PythonVanilla", false},
+		{"C", "C++", false},
+		{"C++", "community/C++", true},
+		{"Node", "Python", false},
+	}
+	for _, test := range tests {
+		if similar := similarName(test.name, test.other); similar != test.similar {
+			t.Errorf("Similarity of %s and %s should be %v, got %v instead", test.name, test.other, test.similar, similar)
+		}
+	}
+}
+
+func TestRenamesOrder(t *testing.T) {
+	found := renames{{Entry: "b"}, {Entry: "c", SameContents: true}, {Entry: "a"}}
+	sort.Stable(found)
+
+	expected := renames{{Entry: "c", SameContents: true}, {Entry: "a"}, {Entry: "b"}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Renames should be ordered %v, got %v instead", expected, found)
+	}
+}
+
+func TestFindRenames(t *testing.T) {
+	const raw = "https://raw.githubusercontent.com/github/gitignore/master/"
+	// The template moved to the last directory, after more similarly named templates than are fetched.
+	paths := []string{"Ada", "Go", "JavaScript", "a/eclipse", "b/ECLIPSE", "c/e-clipse", "d/Eclipse_", "e/eclipse", "f/ecLipse", "z/Eclipse"}
+	var tree struct {
+		Tree []map[string]string `json:"tree"`
+	}
+	for _, path := range paths {
+		tree.Tree = append(tree.Tree, map[string]string{"path": path + ".gitignore", "type": "blob"})
+	}
+	listing, _ := json.Marshal(tree)
+
+	stub := &stubTransport{bodies: map[string]string{
+		"https://api.github.com/repos/github/gitignore/git/trees/master?recursive=1": string(listing),
+		raw + "a/eclipse.gitignore": ".project\n",
+		raw + "z/Eclipse.gitignore": ".metadata\nbin/\n.settings/\n",
+	}}
+	defer stubProviders(stub)()
+
+	source := spec.Source{Provider: "github", Repo: "github/gitignore", Ref: "master", Entries: []spec.Entry{
+		{Name: "Go"},
+		{Name: "Eclipse", Exclude: []string{"bin/"}},
+	}}
+	found, err := FindRenames(source, source.Entries[1], ".metadata\n# ignoreit: excluded bin/\n.settings/\n")
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := []Rename{
+		{Entry: "z/Eclipse", SameContents: true},
+		{Entry: "a/eclipse"},
+		{Entry: "b/ECLIPSE"},
+		{Entry: "c/e-clipse"},
+		{Entry: "d/Eclipse_"},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Renames should be %v, got %v instead", expected, found)
+	}
+}
+
+func TestFindRenamesByContents(t *testing.T) {
+	const raw = "https://raw.githubusercontent.com/github/gitignore/master/"
+	listing := `{"tree": [
+		{"path": "Global/Linux.gitignore", "type": "blob"},
+		{"path": "Global/macOS.gitignore", "type": "blob"},
+		{"path": "Global/vim.gitignore", "type": "blob"}
+	]}`
+	stub := &stubTransport{bodies: map[string]string{
+		"https://api.github.com/repos/github/gitignore/git/trees/master?recursive=1": listing,
+		raw + "Global/Linux.gitignore": "*~\n",
+		raw + "Global/macOS.gitignore": ".DS_Store\n.AppleDouble\n",
+		raw + "Global/vim.gitignore":   "*.swp\n",
+	}}
+	defer stubProviders(stub)()
+
+	source := spec.Source{Provider: "github", Repo: "github/gitignore", Ref: "master", Entries: []spec.Entry{
+		{Name: "Global/OSX"},
+	}}
+	found, err := FindRenames(source, source.Entries[0], ".DS_Store\n.AppleDouble\n")
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expected := []Rename{{Entry: "Global/macOS", SameContents: true}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Renames should be %v, got %v instead", expected, found)
+	}
+	for _, url := range stub.requested {
+		if url == raw+"Global/vim.gitignore" {
+			t.Errorf("Entries should not be fetched once a rename with the same contents is found, got %v instead", stub.requested)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package generate
+
+import (
+	"strings"
+
+	"github.com/whoshuu/ignoreit/pattern"
+)
+
+// TrackedGroup collects the tracked files ignored by the patterns of a single section.
+type TrackedGroup struct {
+	Section string
+	Files   []TrackedFile
+}
+
+// TrackedFile is a file in the git index paired with the generated pattern that would ignore it.
+type TrackedFile struct {
+	Path    string
+	Pattern string
+}
+
+// Matcher compiles the patterns of every section, in output order, into a single matcher.
+// Each pattern's Origin is set to the Name of the section it came from.
+// Invalid patterns are skipped, since git silently ignores them as well.
+func Matcher(sections []Section) pattern.Matcher {
+	var matcher pattern.Matcher
+	for _, section := range sections {
+		for _, line := range strings.Split(section.Contents, "\n") {
+			if p, err := pattern.Parse(line); err == nil && p != nil {
+				p.Origin = section.Name()
+				matcher = append(matcher, p)
+			}
+		}
+	}
+	return matcher
+}
+
+// CheckTracked reports the tracked files that the generated sections would ignore.
+// Git keeps tracking these files regardless, so they usually point at a template that is too broad for the project.
+// Results are grouped by the section owning the deciding pattern, in output order.
+func CheckTracked(sections []Section, files []string) []TrackedGroup {
+	matcher := Matcher(sections)
+	matches := make(map[string][]TrackedFile)
+	for _, file := range files {
+		if p, ignored := matcher.Match(file, false); ignored {
+			matches[p.Origin] = append(matches[p.Origin], TrackedFile{file, p.Text})
+		}
+	}
+
+	var groups []TrackedGroup
+	for _, section := range sections {
+		if files, ok := matches[section.Name()]; ok {
+			groups = append(groups, TrackedGroup{section.Name(), files})
+			delete(matches, section.Name())
+		}
+	}
+	return groups
+}
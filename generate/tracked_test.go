@@ -0,0 +1,40 @@
+package generate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckTracked(t *testing.T) {
+	sections := []Section{
+		{Source: "github/gitignore - master", Entry: "Go", Contents: "*.exe\nbuild/\n"},
+		{Source: "github/gitignore - master", Entry: "Node", Contents: "*.log\n"},
+		{Contents: "!keep.log\n"},
+	}
+	tests := []struct {
+		name   string
+		files  []string
+		groups []TrackedGroup
+	}{
+		{"untracked", []string{"main.go", "README.md"}, nil},
+		{"pattern", []string{"main.go", "app.exe"}, []TrackedGroup{
+			{"Go (github/gitignore - master)", []TrackedFile{{"app.exe", "*.exe"}}},
+		}},
+		{"negated", []string{"debug.log", "keep.log", "logs/keep.log"}, []TrackedGroup{
+			{"Node (github/gitignore - master)", []TrackedFile{{"debug.log", "*.log"}}},
+		}},
+		{"ignored directory", []string{"build/out.txt", "build/keep.log", "src/build.go"}, []TrackedGroup{
+			{"Go (github/gitignore - master)", []TrackedFile{{"build/out.txt", "build/"}, {"build/keep.log", "build/"}}},
+		}},
+		{"output order", []string{"debug.log", "app.exe"}, []TrackedGroup{
+			{"Go (github/gitignore - master)", []TrackedFile{{"app.exe", "*.exe"}}},
+			{"Node (github/gitignore - master)", []TrackedFile{{"debug.log", "*.log"}}},
+		}},
+	}
+	for _, test := range tests {
+		groups := CheckTracked(sections, test.files)
+		if !reflect.DeepEqual(groups, test.groups) {
+			t.Errorf("Tracked groups for %s should be %v, got %v instead", test.name, test.groups, groups)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// TrackedFiles lists every file in the git index of the repository containing dir.
+// Paths are slash separated and relative to dir, matching the output of `git ls-files`.
+func TrackedFiles(dir string) ([]string, error) {
+	out, err := run(dir, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Root returns the absolute path of the top-level directory of the repository containing dir.
+func Root(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// InfoExcludePath returns the path of the per-clone exclude file of the repository containing dir.
+// Patterns in this file apply only to the local clone and are never committed.
+func InfoExcludePath(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// GlobalExcludesPath returns the path of the user's global excludes file.
+// It is read from core.excludesFile, falling back to the XDG default of $XDG_CONFIG_HOME/git/ignore
+// that git itself uses when the setting is absent.
+func GlobalExcludesPath() (string, error) {
+	if out, err := run(".", "config", "--path", "--get", "core.excludesFile"); err == nil && strings.TrimSpace(out) != "" {
+		return strings.TrimSpace(out), nil
+	}
+
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "git", "ignore"), nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("cannot locate global excludes file: core.excludesFile is unset and HOME is empty")
+	}
+	return filepath.Join(home, ".config", "git", "ignore"), nil
+}
+
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
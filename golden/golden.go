@@ -0,0 +1,28 @@
+// Package golden compares the output of tests with the golden files kept in their testdata directory.
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Check compares actual with the contents of the golden file at path.
+// Run the tests with -update to rewrite the golden files after an intentional change of the output.
+func Check(t *testing.T, path string, actual []byte) {
+	if *update {
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("Error writing golden file: %s", err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading golden file: %s", err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("%s should be:\n%s\ngot:\n%s", path, expected, actual)
+	}
+}
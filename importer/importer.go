@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"sort"
+	"strings"
+)
+
+// minMatched is the number of patterns a template must have in common with the file to be accepted, whatever its
+// confidence, since the few patterns of a small template are often found in files that were not built from it.
+const minMatched = 3
+
+// Template is the name and contents of an upstream .gitignore entry to match against.
+type Template struct {
+	Name     string
+	Contents string
+}
+
+// Match reports how much of a template was found in the analyzed file.
+// Confidence is the fraction of the patterns of the template that appear in the file.
+type Match struct {
+	Entry      string
+	Matched    int
+	Total      int
+	Confidence float64
+}
+
+// Result is the outcome of an analysis. Matches holds the templates the file is composed of, in order of confidence,
+// while Rejected holds templates that were found but fell below the threshold or matched too few patterns.
+// Unmatched lists the patterns of the file that no accepted template accounts for, in their original order.
+type Result struct {
+	Matches   []Match
+	Rejected  []Match
+	Unmatched []string
+}
+
+// Analyze identifies which templates the contents of an existing ignore file are composed of.
+// A template is accepted when at least threshold of its patterns, and at least minMatched patterns, appear in the file,
+// and it accounts for some pattern that no larger accepted template already does, so that templates contained in others
+// are not added twice.
+// Comments and blank lines are ignored, and patterns are compared with surrounding whitespace removed.
+func Analyze(contents string, templates []Template, threshold float64) Result {
+	lines := patterns(contents)
+	present := make(map[string]bool)
+	for _, line := range lines {
+		present[line] = true
+	}
+
+	var candidates candidates
+	var result Result
+	for _, template := range templates {
+		var found []string
+		all := patterns(template.Contents)
+		for _, line := range all {
+			if present[line] {
+				found = append(found, line)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		match := Match{template.Name, len(found), len(all), float64(len(found)) / float64(len(all))}
+		if match.Confidence < threshold || match.Matched < minMatched {
+			result.Rejected = append(result.Rejected, match)
+			continue
+		}
+		candidates = append(candidates, candidate{match, found})
+	}
+
+	// Larger templates claim their patterns first.
+	sort.Stable(candidates)
+	claimed := make(map[string]bool)
+	for _, candidate := range candidates {
+		claims := false
+		for _, line := range candidate.lines {
+			if !claimed[line] {
+				claimed[line] = true
+				claims = true
+			}
+		}
+		if claims {
+			result.Matches = append(result.Matches, candidate.Match)
+		}
+	}
+
+	for _, line := range lines {
+		if !claimed[line] {
+			result.Unmatched = append(result.Unmatched, line)
+			claimed[line] = true
+		}
+	}
+
+	sort.Stable(byConfidence(result.Matches))
+	sort.Stable(byConfidence(result.Rejected))
+	return result
+}
+
+// patterns returns the unique patterns of an ignore file in order, without comments and blank lines.
+func patterns(contents string) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// candidate is a template above the threshold, along with the patterns of the file it was found to contain.
+type candidate struct {
+	Match
+	lines []string
+}
+
+// candidates sort by the number of matched patterns, largest first.
+type candidates []candidate
+
+func (c candidates) Len() int {
+	return len(c)
+}
+
+func (c candidates) Less(i, j int) bool {
+	if c[i].Matched != c[j].Matched {
+		return c[i].Matched > c[j].Matched
+	}
+	return c[i].Entry < c[j].Entry
+}
+
+func (c candidates) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// byConfidence sorts matches by confidence, highest first.
+type byConfidence []Match
+
+func (matches byConfidence) Len() int {
+	return len(matches)
+}
+
+func (matches byConfidence) Less(i, j int) bool {
+	if matches[i].Confidence != matches[j].Confidence {
+		return matches[i].Confidence > matches[j].Confidence
+	}
+	return matches[i].Entry < matches[j].Entry
+}
+
+func (matches byConfidence) Swap(i, j int) {
+	matches[i], matches[j] = matches[j], matches[i]
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	templates := []Template{
+		{"Go", "# Binaries\n*.exe\n*.dll\n*.so\n*.test\n*.out\n"},
+		{"Go.AllowList", "*\n!*.go\n"},
+		{"Linux", "*~\n.directory\n.Trash-*\n"},
+		{"Dylib", "*.so\n*.dylib\n"},
+		{"Python", "__pycache__/\n*.py[cod]\n*.so\n"},
+	}
+	contents := "# Hand maintained\n*.exe\n*.dll\n*.so\n*.test\n\n*~\n.Trash-*\n.directory\n/bin/\n*.exe\n"
+
+	result := Analyze(contents, templates, 0.6)
+
+	expectedMatches := []Match{
+		{"Linux", 3, 3, 1},
+		{"Go", 4, 5, 0.8},
+	}
+	if !reflect.DeepEqual(result.Matches, expectedMatches) {
+		t.Errorf("Matches should be %v, got %v instead", expectedMatches, result.Matches)
+	}
+
+	expectedRejected := []Match{
+		{"Dylib", 1, 2, 0.5},
+		{"Python", 1, 3, 1.0 / 3},
+	}
+	if !reflect.DeepEqual(result.Rejected, expectedRejected) {
+		t.Errorf("Rejected should be %v, got %v instead", expectedRejected, result.Rejected)
+	}
+
+	expectedUnmatched := []string{"/bin/"}
+	if !reflect.DeepEqual(result.Unmatched, expectedUnmatched) {
+		t.Errorf("Unmatched should be %v, got %v instead", expectedUnmatched, result.Unmatched)
+	}
+}
+
+func TestAnalyzeSkipsContainedTemplates(t *testing.T) {
+	templates := []Template{
+		{"C++", "*.o\n*.obj\n*.a\n"},
+		{"Objects", "*.o\n*.obj\n"},
+	}
+
+	result := Analyze("*.o\n*.obj\n*.a\n", templates, 0.8)
+
+	if len(result.Matches) != 1 || result.Matches[0].Entry != "C++" {
+		t.Errorf("Only C++ should match, got %v instead", result.Matches)
+	}
+	if len(result.Unmatched) != 0 {
+		t.Errorf("Every line should be matched, got %v unmatched instead", result.Unmatched)
+	}
+}
+
+func TestAnalyzeRequiresMinimumMatches(t *testing.T) {
+	templates := []Template{
+		{"Vim", "*.swp\n*~\n"},
+		{"Linux", "*~\n.directory\n.Trash-*\n"},
+	}
+
+	result := Analyze("*.swp\n*~\n/dist/\n", templates, 0.8)
+
+	if len(result.Matches) != 0 {
+		t.Errorf("Templates with fewer than %d patterns in common should not match, got %v instead", minMatched, result.Matches)
+	}
+	expectedRejected := []Match{{"Vim", 2, 2, 1}, {"Linux", 1, 3, 1.0 / 3}}
+	if !reflect.DeepEqual(result.Rejected, expectedRejected) {
+		t.Errorf("Rejected should be %v, got %v instead", expectedRejected, result.Rejected)
+	}
+	expectedUnmatched := []string{"*.swp", "*~", "/dist/"}
+	if !reflect.DeepEqual(result.Unmatched, expectedUnmatched) {
+		t.Errorf("Unmatched should be %v, got %v instead", expectedUnmatched, result.Unmatched)
+	}
+}
@@ -0,0 +1,69 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/whoshuu/ignoreit/generate"
+	"github.com/whoshuu/ignoreit/pattern"
+	"github.com/whoshuu/ignoreit/report"
+)
+
+// Run walks the tree rooted at root and evaluates every pattern of the sections against each path.
+// The .git directory is never walked. Patterns are matched individually, without regard to negation or ordering,
+// so a pattern counts as live whenever it would match some path if it were the only rule.
+func Run(sections []generate.Section, root string) (report.Lint, error) {
+	result := report.Lint{Root: root, Sections: []report.LintSection{}}
+
+	var patterns []pattern.Matcher
+	for _, section := range sections {
+		compiled := generate.Matcher([]generate.Section{section})
+		sectionReport := report.LintSection{Section: section.Name(), Patterns: []report.LintPattern{}, Dead: []string{}}
+		for _, p := range compiled {
+			sectionReport.Patterns = append(sectionReport.Patterns, report.LintPattern{Pattern: p.Text})
+		}
+		patterns = append(patterns, compiled)
+		result.Sections = append(result.Sections, sectionReport)
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		result.Paths++
+		for i := range patterns {
+			hit := false
+			for j, p := range patterns[i] {
+				if p.Match(rel, info.IsDir()) {
+					result.Sections[i].Patterns[j].Hits++
+					hit = true
+				}
+			}
+			if hit {
+				result.Sections[i].Hits++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for i := range result.Sections {
+		for _, p := range result.Sections[i].Patterns {
+			if p.Hits == 0 {
+				result.Sections[i].Dead = append(result.Sections[i].Dead, p.Pattern)
+			}
+		}
+	}
+	return result, nil
+}
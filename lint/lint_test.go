@@ -0,0 +1,60 @@
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/generate"
+)
+
+func TestRun(t *testing.T) {
+	root, err := ioutil.TempDir("", "ignoreit-lint")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, path := range []string{"main.go", "main.o", "build/out.bin", ".git/HEAD"} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	sections := []generate.Section{
+		{Source: "github/gitignore - master", Entry: "C", Contents: "# Objects\n*.o\n*.so\n"},
+		{Contents: "build/\nHEAD\n"},
+	}
+
+	report, err := Run(sections, root)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	if report.Paths != 4 {
+		t.Errorf("Should have walked 4 paths, walked %d instead", report.Paths)
+	}
+
+	expected := []struct {
+		hits int
+		dead []string
+	}{
+		{1, []string{"*.so"}},
+		{1, []string{"HEAD"}},
+	}
+
+	for i, e := range expected {
+		section := report.Sections[i]
+		if section.Hits != e.hits {
+			t.Errorf("Section %s should have %d hits, got %d instead", section.Section, e.hits, section.Hits)
+		}
+		if len(section.Dead) != len(e.dead) || section.Dead[0] != e.dead[0] {
+			t.Errorf("Section %s should have dead patterns %v, got %v instead", section.Section, e.dead, section.Dead)
+		}
+	}
+}
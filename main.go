@@ -1,97 +1,1680 @@
 package main
 
 import (
-	"log"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
 
 	"github.com/urfave/cli"
 
+	"github.com/whoshuu/ignoreit/detect"
+	"github.com/whoshuu/ignoreit/diff"
 	"github.com/whoshuu/ignoreit/generate"
+	"github.com/whoshuu/ignoreit/git"
+	"github.com/whoshuu/ignoreit/importer"
+	"github.com/whoshuu/ignoreit/lint"
+	"github.com/whoshuu/ignoreit/network"
+	"github.com/whoshuu/ignoreit/pattern"
+	"github.com/whoshuu/ignoreit/report"
 	"github.com/whoshuu/ignoreit/spec"
 )
 
+const (
+	// maxRejected limits how many templates that were not imported are reported.
+	maxRejected = 5
+	// fetchWorkers limits how many templates are downloaded at once when importing.
+	fetchWorkers = 16
+	// diffContext is the number of unchanged lines shown around upstream changes by update.
+	diffContext = 3
+)
+
 const (
 	configFilename = ".ignoreit.yml"
 	ignoreFilename = ".gitignore"
 	defaultRepo    = "github/gitignore"
-	defaultBranch  = "master"
+	defaultRef     = "master"
 )
 
-func main() {
-	config, err := spec.LoadConfig(configFilename)
-	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
-	}
+// version is recorded in the header of generated files, and can be set at build time with
+// -ldflags "-X main.version=VERSION".
+var version = "dev"
 
+func main() {
 	app := cli.NewApp()
 	app.Name = "ignoreit"
 	app.Usage = "Manage .gitignore templates declaratively"
+	app.Version = version
+	generate.Version = version
+
+	// The spec and outputs are only located and loaded by the commands that need them, so that help
+	// is always available and errors are reported by the command they affect.
+	var configPath string
+	var outputPath string
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "config, c",
+			Usage:       "use the spec at `PATH` instead of searching for " + configFilename + " up to the repository root",
+			EnvVar:      "IGNOREIT_CONFIG",
+			Destination: &configPath,
+		},
+		cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "write the generated patterns to `PATH` instead of the outputs declared by the spec",
+			EnvVar:      "IGNOREIT_OUTPUT",
+			Destination: &outputPath,
+		},
+	}
 
+	userFlag := cli.BoolFlag{
+		Name:  "user, u",
+		Usage: "use the user-level ~/" + configFilename + " for personal entries instead of the project spec",
+	}
+
+	var provider string
 	var repo string
-	var branch string
+	var ref string
 	addAndRemoveFlags := []cli.Flag{
+		userFlag,
+		cli.StringFlag{
+			Name:        "provider, p",
+			Value:       spec.DefaultProvider,
+			Usage:       "git hosting `PROVIDER` of the REPO, one of: " + strings.Join(spec.Providers(), ", "),
+			Destination: &provider,
+		},
 		cli.StringFlag{
 			Name:        "repo, r",
 			Value:       defaultRepo,
-			Usage:       "uses .gitignore files from https://github.com/`REPO`",
+			Usage:       "uses .gitignore files from the `REPO` hosted by the PROVIDER",
 			Destination: &repo,
 		},
 		cli.StringFlag{
-			Name:        "branch, b",
-			Value:       defaultBranch,
-			Usage:       "git `BRANCH` of the REPO",
-			Destination: &branch,
+			Name:        "ref, branch, b",
+			Value:       defaultRef,
+			Usage:       "git branch, tag or commit `REF` of the REPO",
+			Destination: &ref,
 		},
 	}
+	formatFlag := cli.StringFlag{
+		Name:  "format, f",
+		Value: "text",
+		Usage: "output `FORMAT`, either text or json",
+	}
 	app.Commands = []cli.Command{
+		{
+			Name:      "init",
+			Usage:     "create " + configFilename + " with entries for the languages and tools detected in the working tree",
+			ArgsUsage: "[ENTRY...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "add the detected entries without asking for confirmation",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				configPath, err := locateConfig(configPath)
+				if err != nil {
+					return err
+				}
+				if _, err = os.Stat(configPath); err == nil {
+					return fmt.Errorf("%s already exists, use add to change its entries", displayPath(configPath))
+				}
+				options, err := newGenerateOptions(c, outputPath)
+				if err != nil {
+					return err
+				}
+				config, err := spec.LoadConfig(configPath)
+				if err != nil {
+					return fmt.Errorf("Error loading config %s: %v", configPath, err)
+				}
+
+				matches, err := detect.Detect(filepath.Dir(configPath))
+				if err != nil {
+					return fmt.Errorf("Error scanning working tree: %v", err)
+				}
+				if len(matches) == 0 {
+					fmt.Println("No languages or tools detected, creating a spec without entries")
+				} else if matches, err = selectMatches(matches, c.Args(), c.Bool("yes"), os.Stdin, os.Stdout); err != nil {
+					return err
+				}
+
+				source := config.CreateSource(spec.DefaultProvider, defaultRepo, defaultRef)
+				for _, match := range matches {
+					if err = source.AddEntry(match.Entry); err != nil {
+						return fmt.Errorf("Error adding entry: %v", err)
+					}
+				}
+				if err = config.Save(configPath); err != nil {
+					return err
+				}
+				fmt.Printf("Created %s\n", displayPath(configPath))
+				_, err = generateConfig(config, configPath, options, os.Stderr)
+				return err
+			},
+		},
 		{
 			// Add source and branch flags
 			Name:    "add",
 			Aliases: []string{"a"},
 			Usage:   "add entries to .ignoreit.yml",
-			Flags:   addAndRemoveFlags,
+			Flags:   append([]cli.Flag{formatFlag}, addAndRemoveFlags...),
 			Action: func(c *cli.Context) error {
-				source := config.CreateSource(repo, branch)
-				var err error
-				if source != nil {
+				return runCommand(c, func() (interface{}, error) {
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					source := config.CreateSource(provider, repo, ref)
+					if source == nil {
+						return nil, report.Coded(report.CodeUsage, fmt.Errorf("Provider, repo and ref must not be empty"))
+					}
+
+					result := report.Add{Config: displayPath(filename), Source: source.String(), Added: []string{}, Skipped: []report.Skipped{}}
 					for _, entry := range c.Args() {
+						if source.GetEntry(entry) != nil {
+							result.Skipped = append(result.Skipped, report.Skipped{Entry: entry, Reason: "already in the spec"})
+							continue
+						}
 						if err = source.AddEntry(entry); err != nil {
-							log.Fatalf("Error adding entry: %v", err)
+							return nil, fmt.Errorf("Error adding entry: %v", err)
+						}
+						if source.GetEntry(entry) == nil {
+							result.Skipped = append(result.Skipped, report.Skipped{Entry: entry, Reason: "not found in " + source.String()})
+						} else {
+							result.Added = append(result.Added, entry)
 						}
 					}
-					return config.Save(configFilename)
-				}
-				return err
+					return result, report.Coded(report.CodeConfig, config.Save(filename))
+				})
 			},
 		},
 		{
 			Name:    "remove",
 			Aliases: []string{"rm"},
 			Usage:   "remove entries to .ignoreit.yml",
-			Flags:   addAndRemoveFlags,
+			Flags:   append([]cli.Flag{formatFlag}, addAndRemoveFlags...),
 			Action: func(c *cli.Context) error {
-				source := config.GetSource(repo, branch)
-				var err error
-				if source != nil {
+				return runCommand(c, func() (interface{}, error) {
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+
+					described := spec.Source{Provider: provider, Repo: repo, Ref: ref}.String()
+					result := report.Remove{Config: displayPath(filename), Source: described, Removed: []string{}, Skipped: []report.Skipped{}}
+					source := config.GetSource(provider, repo, ref)
 					for _, entry := range c.Args() {
+						if source == nil || source.GetEntry(entry) == nil {
+							result.Skipped = append(result.Skipped, report.Skipped{Entry: entry, Reason: "not in the spec"})
+							continue
+						}
 						if err = source.RemoveEntry(entry); err != nil {
-							log.Fatalf("Error removing entry: %v", err)
+							return nil, fmt.Errorf("Error removing entry: %v", err)
 						}
+						result.Removed = append(result.Removed, entry)
+					}
+					if source == nil {
+						return result, nil
 					}
-					return config.Save(configFilename)
+					return result, report.Coded(report.CodeConfig, config.Save(filename))
+				})
+			},
+		},
+		{
+			Name:      "mv",
+			Usage:     "rename an entry of .ignoreit.yml, move it to another source or change its position",
+			ArgsUsage: "ENTRY [NEW_NAME]",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "move the entry to the source `[PROVIDER:]REPO[@REF]`, adding the source if it doesn't exist",
+				},
+				cli.StringFlag{
+					Name:  "before",
+					Usage: "place the entry right before `ENTRY` of its source",
+				},
+				cli.StringFlag{
+					Name:  "after",
+					Usage: "place the entry right after `ENTRY` of its source",
+				},
+			}, addAndRemoveFlags...),
+			Action: func(c *cli.Context) error {
+				if c.NArg() < 1 || c.NArg() > 2 {
+					return fmt.Errorf("Expected ENTRY and an optional NEW_NAME, got %d arguments", c.NArg())
 				}
-				return err
+				name, newName := c.Args().Get(0), c.Args().Get(0)
+				if c.NArg() == 2 {
+					newName = c.Args().Get(1)
+				}
+				config, filename, err := selectConfig(c, configPath)
+				if err != nil {
+					return err
+				}
+
+				to := config.GetSource(provider, repo, ref)
+				if reference := c.String("to"); reference != "" {
+					to = nil
+					for i := range config.Sources {
+						if config.Sources[i].Matches(reference) {
+							to = &config.Sources[i]
+							break
+						}
+					}
+					if to == nil {
+						toProvider, toRepo, toRef := spec.ParseReference(reference)
+						if toRef == "" {
+							toRef = defaultRef
+						}
+						if to = config.CreateSource(toProvider, toRepo, toRef); to == nil {
+							return fmt.Errorf("Invalid source %s", reference)
+						}
+					}
+				}
+				// Look the source up after the destination, since creating a source can move the others.
+				from := config.GetSource(provider, repo, ref)
+				if from == nil {
+					return fmt.Errorf("%s has no source %s", displayPath(filename), spec.Source{Provider: provider, Repo: repo, Ref: ref})
+				}
+				if from.GetEntry(name) == nil {
+					return fmt.Errorf("%s is not an entry of %s", name, from)
+				}
+
+				if from != to || name != newName {
+					entry := *from.GetEntry(name)
+					entry.Name = newName
+					if to.GetEntry(newName) == nil && !network.EntryExists(to.GetDownloadLink(entry)) {
+						return fmt.Errorf("%s not found in %s", newName, to)
+					}
+				}
+				if err = spec.MoveEntry(from, to, name, newName, c.String("before"), c.String("after")); err != nil {
+					return err
+				}
+				if (c.String("before") != "" || c.String("after") != "") && config.Order != spec.OrderPreserve {
+					fmt.Fprintf(os.Stderr, "Warning: entries are sorted when %s is saved, set order: %s to keep this position\n", displayPath(filename), spec.OrderPreserve)
+				}
+				// Saving sorts and compacts the sources, so from and to may point at other sources afterwards.
+				moved := fmt.Sprintf("Moved %s (%s) to %s (%s)", name, from, newName, to)
+				if err = config.Save(filename); err != nil {
+					return err
+				}
+				fmt.Println(moved)
+				return nil
+			},
+		},
+		{
+			Name:  "custom",
+			Usage: "manage the custom patterns of .ignoreit.yml",
+			Subcommands: []cli.Command{
+				{
+					Name:      "add",
+					Usage:     "add custom patterns to .ignoreit.yml, skipping those already in it",
+					ArgsUsage: "PATTERN...",
+					Flags: []cli.Flag{
+						userFlag,
+						cli.StringFlag{
+							Name:  "comment, m",
+							Usage: "write `COMMENT` above the added patterns",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						if c.NArg() == 0 {
+							return fmt.Errorf("Expected at least one PATTERN")
+						}
+						var patterns []*pattern.Pattern
+						for _, arg := range c.Args() {
+							p, err := pattern.Parse(arg)
+							if err != nil {
+								return err
+							}
+							if p == nil || strings.Contains(arg, "\n") {
+								return fmt.Errorf("%q is not a pattern, use --comment to add a comment", arg)
+							}
+							patterns = append(patterns, p)
+						}
+						options, err := newGenerateOptions(c, outputPath)
+						if err != nil {
+							return err
+						}
+						config, filename, err := selectConfig(c, configPath)
+						if err != nil {
+							return err
+						}
+
+						sections := customSections(*config, filename, options)
+						comment := c.String("comment")
+						added := 0
+						for _, p := range patterns {
+							if !config.AddCustom(p.Text, comment) {
+								fmt.Printf("Skipped %s: already a custom pattern\n", p.Text)
+								continue
+							}
+							// The comment documents the whole batch, so it is only written above the first pattern.
+							comment = ""
+							added++
+							fmt.Printf("Added %s\n", p.Text)
+							if coverage := customCoverage(sections, p); coverage != "" {
+								fmt.Fprintf(os.Stderr, "Warning: %s is %s\n", p.Text, coverage)
+							}
+						}
+						if added == 0 {
+							return nil
+						}
+						return config.Save(filename)
+					},
+				},
+				{
+					Name:      "remove",
+					Aliases:   []string{"rm"},
+					Usage:     "remove custom patterns, and the comments above them, from .ignoreit.yml",
+					ArgsUsage: "PATTERN...",
+					Flags:     []cli.Flag{userFlag},
+					Action: func(c *cli.Context) error {
+						config, filename, err := selectConfig(c, configPath)
+						if err != nil {
+							return err
+						}
+
+						removed := 0
+						for _, arg := range c.Args() {
+							if !config.RemoveCustom(arg) {
+								fmt.Printf("Skipped %s: not a custom pattern\n", arg)
+								continue
+							}
+							removed++
+							fmt.Printf("Removed %s\n", arg)
+						}
+						if removed == 0 {
+							return nil
+						}
+						return config.Save(filename)
+					},
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "show the custom patterns of .ignoreit.yml and those it includes, noting the ones entries already decide",
+					Flags:   []cli.Flag{userFlag},
+					Action: func(c *cli.Context) error {
+						options, err := newGenerateOptions(c, outputPath)
+						if err != nil {
+							return err
+						}
+						config, filename, err := selectConfig(c, configPath)
+						if err != nil {
+							return err
+						}
+						expanded, err := config.Expand(filename)
+						if err != nil {
+							return err
+						}
+						if len(expanded.Custom) == 0 {
+							fmt.Println("No custom patterns")
+							return nil
+						}
+
+						sections := customSections(*config, filename, options)
+						for _, line := range expanded.Custom {
+							p, err := pattern.Parse(line)
+							if err != nil || p == nil {
+								fmt.Println(line)
+								continue
+							}
+							if coverage := customCoverage(sections, p); coverage != "" {
+								fmt.Printf("%s (%s)\n", line, coverage)
+							} else {
+								fmt.Println(line)
+							}
+						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "add the entries an existing .gitignore is composed of to .ignoreit.yml",
+			ArgsUsage: "[FILE]",
+			Flags: append([]cli.Flag{
+				cli.Float64Flag{
+					Name:  "threshold",
+					Value: 0.8,
+					Usage: "fraction of the patterns of an entry that must be found in FILE for it to be added",
+				},
+			}, addAndRemoveFlags...),
+			Action: func(c *cli.Context) error {
+				threshold := c.Float64("threshold")
+				if threshold <= 0 || threshold > 1 {
+					return fmt.Errorf("Threshold must be greater than 0 and at most 1, got %v", threshold)
+				}
+				config, filename, err := selectConfig(c, configPath)
+				if err != nil {
+					return err
+				}
+				source := config.CreateSource(provider, repo, ref)
+				if source == nil {
+					return fmt.Errorf("Provider, repo and ref must not be empty")
+				}
+
+				ignorePath := c.Args().First()
+				if ignorePath == "" {
+					ignorePath = outputPath
+				}
+				if ignorePath == "" {
+					ignorePath = filepath.Join(filepath.Dir(filename), ignoreFilename)
+				}
+				contents, err := ioutil.ReadFile(ignorePath)
+				if err != nil {
+					return fmt.Errorf("Error reading %s: %v", ignorePath, err)
+				}
+
+				names, err := source.ListEntries()
+				if err != nil {
+					return err
+				}
+				result := importer.Analyze(string(contents), fetchTemplates(*source, names), threshold)
+
+				for _, match := range result.Matches {
+					if source.GetEntry(match.Entry) == nil {
+						source.Entries = append(source.Entries, spec.Entry{Name: match.Entry})
+					}
+				}
+				config.Custom = appendMissing(config.Custom, result.Unmatched)
+				if err = config.Save(filename); err != nil {
+					return err
+				}
+
+				writeImportReport(os.Stdout, *source, result, threshold)
+				fmt.Printf("Run ignoreit generate to replace %s with the generated patterns\n", displayPath(ignorePath))
+				return nil
 			},
 		},
 		{
 			Name:    "generate",
 			Aliases: []string{"g"},
 			Usage:   "generate a .gitignore from .ignoreit.yml",
+			Flags: []cli.Flag{
+				userFlag,
+				cli.BoolFlag{
+					Name:  "check-tracked, t",
+					Usage: "warn about files in the git index that the generated patterns would ignore",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "fail instead of warning when tracked files would be ignored (implies --check-tracked)",
+				},
+				cli.BoolFlag{
+					Name:  "recursive, R",
+					Usage: "generate every " + configFilename + " found under the repository root",
+				},
+				cli.BoolFlag{
+					Name:  "follow-renames",
+					Usage: "rename entries removed upstream to a listed entry with the same contents",
+				},
+				cli.BoolFlag{
+					Name:  "resolve-refs",
+					Usage: "fetch entries at the commit their branch or tag points at, and record it in the header",
+				},
+				formatFlag,
+			},
+			Action: func(c *cli.Context) error {
+				return runCommand(c, func() (interface{}, error) {
+					options, err := newGenerateOptions(c, outputPath)
+					if err != nil {
+						return nil, report.Coded(report.CodeUsage, err)
+					}
+					if c.Bool("recursive") {
+						if options.output != "" {
+							return nil, report.Coded(report.CodeUsage, fmt.Errorf("--output cannot be used with --recursive, since every config would write to it"))
+						}
+						return generateRecursive(options)
+					}
+
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					return generateConfig(*config, filename, options, os.Stderr)
+				})
+			},
+		},
+		{
+			Name:      "update",
+			Usage:     "show upstream changes to every entry and regenerate with the accepted ones",
+			ArgsUsage: "[ENTRY...]",
+			Flags: []cli.Flag{
+				userFlag,
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "accept the changes to every entry without asking",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				config, filename, err := selectConfig(c, configPath)
+				if err != nil {
+					return err
+				}
+
+				selected := make(map[string]bool)
+				for _, entry := range c.Args() {
+					selected[entry] = true
+				}
+				reader := bufio.NewReader(os.Stdin)
+				ask := func(question string) (bool, error) {
+					fmt.Printf("%s [y/N] ", question)
+					answer, err := reader.ReadString('\n')
+					if err != nil && answer == "" {
+						return false, fmt.Errorf("Error reading confirmation: %v, pass --yes or the entries to accept", err)
+					}
+					answer = strings.ToLower(strings.TrimSpace(answer))
+					return answer == "y" || answer == "yes", nil
+				}
+				accept := func(section generate.Section) (bool, error) {
+					if len(selected) > 0 {
+						return selected[section.Entry], nil
+					}
+					if c.Bool("yes") {
+						return true, nil
+					}
+					return ask(fmt.Sprintf("Accept changes to %s?", section.Name()))
+				}
+				options, err := newGenerateOptions(c, outputPath)
+				if err != nil {
+					return err
+				}
+				options.rename = func(section generate.Section, renames []generate.Rename) (string, error) {
+					if len(selected) > 0 && !selected[section.Entry] {
+						return "", nil
+					}
+					if len(selected) > 0 || c.Bool("yes") {
+						return followSameContents(section, renames)
+					}
+					for _, rename := range renames {
+						question := fmt.Sprintf("%s was removed upstream, rename it to %s", section.Name(), rename.Entry)
+						if rename.SameContents {
+							question += " with the same contents"
+						}
+						ok, err := ask(question + "?")
+						if err != nil || ok {
+							return rename.Entry, err
+						}
+					}
+					return "", nil
+				}
+				return updateConfig(*config, filename, options, accept)
+			},
+		},
+		{
+			Name:  "migrate",
+			Usage: "rewrite .ignoreit.yml at the latest schema version",
+			Flags: []cli.Flag{userFlag},
+			Action: func(c *cli.Context) error {
+				config, filename, err := selectConfig(c, configPath)
+				if err != nil {
+					return err
+				}
+				version, err := spec.Migrate(filename)
+				if err != nil {
+					return fmt.Errorf("Error migrating %s: %v", filename, err)
+				}
+				if version == config.SchemaVersion {
+					fmt.Printf("%s is already at schema version %d\n", filename, version)
+				} else {
+					fmt.Printf("Migrated %s from schema version %d to %d\n", filename, version, config.SchemaVersion)
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "convert",
+			Usage:     "rewrite .ignoreit.yml in another spec format, either yaml, toml or json",
+			ArgsUsage: "FORMAT",
+			Flags: []cli.Flag{
+				userFlag,
+				cli.BoolFlag{
+					Name:  "keep",
+					Usage: "keep the spec in its previous format instead of removing it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fmt.Errorf("Expected a FORMAT, either %s, %s or %s", spec.SpecYAML, spec.SpecTOML, spec.SpecJSON)
+				}
+				config, filename, err := selectConfig(c, configPath)
+				if err != nil {
+					return err
+				}
+				if _, err = os.Stat(filename); err != nil {
+					return fmt.Errorf("Error converting %s: %v", displayPath(filename), err)
+				}
+				converted, err := spec.SpecFilename(filename, c.Args().First())
+				if err != nil {
+					return err
+				}
+				if converted == filename {
+					fmt.Printf("%s is already in %s format\n", displayPath(filename), c.Args().First())
+					return nil
+				}
+				if _, err = os.Stat(converted); err == nil {
+					return fmt.Errorf("%s already exists", displayPath(converted))
+				}
+
+				if err = config.Save(converted); err != nil {
+					return err
+				}
+				fmt.Printf("Converted %s to %s\n", displayPath(filename), displayPath(converted))
+				if c.Bool("keep") {
+					if found, _ := spec.ConfigIn(filepath.Dir(filename), filepath.Base(filename)); found != converted {
+						fmt.Fprintf(os.Stderr, "Warning: %s takes precedence over %s while both exist\n", displayPath(found), displayPath(converted))
+					}
+					return nil
+				}
+				return os.Remove(filename)
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "show the sources and entries of .ignoreit.yml and whether the generated files and upstream match them",
+			Flags: []cli.Flag{
+				userFlag,
+				cli.BoolFlag{
+					Name:  "offline",
+					Usage: "skip fetching entries to compare them with upstream",
+				},
+				formatFlag,
+			},
 			Action: func(c *cli.Context) error {
-				return generate.Inflate(config, ignoreFilename)
+				return runCommand(c, func() (interface{}, error) {
+					options, err := newGenerateOptions(c, outputPath)
+					if err != nil {
+						return nil, report.Coded(report.CodeUsage, err)
+					}
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					return statusConfig(*config, filename, options, !c.Bool("offline"))
+				})
+			},
+		},
+		{
+			Name:    "lint",
+			Aliases: []string{"l"},
+			Usage:   "report generated patterns that match nothing in the working tree",
+			Flags:   []cli.Flag{formatFlag},
+			Action: func(c *cli.Context) error {
+				return runCommand(c, func() (interface{}, error) {
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					expanded, err := config.Expand(filename)
+					if err != nil {
+						return nil, report.Coded(report.CodeConfig, err)
+					}
+					sections, err := generate.Resolve(expanded)
+					if err != nil {
+						return nil, report.Coded(report.CodeConfig, err)
+					}
+					result, err := lint.Run(sections, filepath.Dir(filename))
+					result.Config, result.Root = displayPath(filename), displayPath(result.Root)
+					if err != nil {
+						return result, fmt.Errorf("Error walking working tree: %v", err)
+					}
+					return result, nil
+				})
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "list the entries and custom patterns of .ignoreit.yml, including those of the specs it includes",
+			Flags: []cli.Flag{userFlag, formatFlag},
+			Action: func(c *cli.Context) error {
+				return runCommand(c, func() (interface{}, error) {
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					expanded, err := config.Expand(filename)
+					if err != nil {
+						return nil, report.Coded(report.CodeConfig, err)
+					}
+					return listConfig(expanded, filename), nil
+				})
+			},
+		},
+		{
+			Name:      "explain",
+			Usage:     "show which generated pattern decides whether each path is ignored",
+			ArgsUsage: "PATH...",
+			Flags:     []cli.Flag{userFlag, formatFlag},
+			Action: func(c *cli.Context) error {
+				return runCommand(c, func() (interface{}, error) {
+					if len(c.Args()) == 0 {
+						return nil, report.Coded(report.CodeUsage, fmt.Errorf("No paths to explain"))
+					}
+					config, filename, err := selectConfig(c, configPath)
+					if err != nil {
+						return nil, err
+					}
+					expanded, err := config.Expand(filename)
+					if err != nil {
+						return nil, report.Coded(report.CodeConfig, err)
+					}
+					sections, err := generate.Resolve(expanded)
+					if err != nil {
+						return nil, report.Coded(report.CodeConfig, err)
+					}
+					return explainPaths(sections, filename, c.Args())
+				})
 			},
 		},
 	}
 
 	app.Run(os.Args)
 }
+
+// runCommand runs a command that supports the --format flag, writing its result as text or as a JSON document.
+// In JSON mode, errors are written as part of the document instead, and the command exits with a non-zero status.
+func runCommand(c *cli.Context, run func() (interface{}, error)) error {
+	format := c.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("Unknown format %q, expected text or json", format)
+	}
+
+	result, err := run()
+	if format == "text" {
+		if writer, ok := result.(report.TextWriter); ok {
+			if werr := writer.WriteText(os.Stdout); werr != nil && err == nil {
+				err = werr
+			}
+		}
+		return err
+	}
+
+	if werr := report.WriteJSON(os.Stdout, c.Command.Name, result, err); werr != nil {
+		return werr
+	}
+	if err != nil {
+		return cli.NewExitError("", 1)
+	}
+	return nil
+}
+
+// listConfig describes the sources, entries and custom patterns of the expanded config loaded from filename.
+func listConfig(expanded spec.Config, filename string) report.List {
+	result := report.List{Config: displayPath(filename), Sources: []report.Source{}, Groups: []report.Group{}, Custom: []string{}}
+	for _, source := range expanded.Sources {
+		listed := report.Source{Name: source.String(), Provider: source.Provider, Repo: source.Repo, Ref: source.Ref, Entries: []report.Entry{}}
+		for _, entry := range source.Entries {
+			listed.Entries = append(listed.Entries, report.Entry{Name: entry.Name, Ref: entry.Ref, Exclude: entry.Exclude, Append: entry.Append})
+		}
+		result.Sources = append(result.Sources, listed)
+	}
+	for _, group := range expanded.Groups {
+		result.Groups = append(result.Groups, report.Group{Name: group.Name, Patterns: append([]string{}, group.Patterns...)})
+	}
+	result.Custom = append(result.Custom, expanded.Custom...)
+	return result
+}
+
+// explainPaths finds the generated pattern deciding whether each path is ignored.
+// Paths are relative to the working directory, and are matched relative to the directory of the config loaded from filename.
+func explainPaths(sections []generate.Section, filename string, paths []string) (report.Explain, error) {
+	result := report.Explain{Config: displayPath(filename), Paths: []report.Explanation{}}
+	matcher := generate.Matcher(sections)
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return result, err
+	}
+
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return result, err
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return result, report.Coded(report.CodeUsage, fmt.Errorf("%s is not under %s", path, displayPath(dir)))
+		}
+
+		isDir := strings.HasSuffix(path, "/")
+		if info, err := os.Stat(abs); err == nil {
+			isDir = info.IsDir()
+		}
+
+		explanation := report.Explanation{Path: path}
+		if p, ignored := matcher.Match(filepath.ToSlash(rel), isDir); p != nil {
+			explanation.Ignored = ignored
+			explanation.Pattern = p.Text
+			explanation.Section = p.Origin
+		}
+		result.Paths = append(result.Paths, explanation)
+	}
+	return result, nil
+}
+
+// customSections returns the entry sections last generated for the config loaded from filename,
+// which custom patterns are checked against without fetching anything.
+// Problems reading them are reported as warnings, since they only prevent the check.
+func customSections(config spec.Config, filename string, options generateOptions) []generate.Section {
+	var sections []generate.Section
+	expanded, err := config.Expand(filename)
+	if err == nil {
+		sections, err = generatedEntries(expanded, filename, options)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: custom patterns are not checked against the entries: %v\n", err)
+	}
+	return sections
+}
+
+// customCoverage describes how the generated entries already decide the paths of a custom pattern,
+// or returns an empty string when they don't, or when the custom pattern re-includes paths they ignore.
+func customCoverage(sections []generate.Section, custom *pattern.Pattern) string {
+	covering := generate.Covering(sections, custom)
+	switch {
+	case covering == nil:
+		return ""
+	case covering.Negate && !custom.Negate:
+		return fmt.Sprintf("negated by %q from %s, which it overrides", covering.Text, covering.Origin)
+	case covering.Negate:
+		return fmt.Sprintf("already re-included by %q from %s", covering.Text, covering.Origin)
+	case !custom.Negate:
+		return fmt.Sprintf("already covered by %q from %s", covering.Text, covering.Origin)
+	}
+	return ""
+}
+
+// locateConfig returns configPath when it is set, or the path of the project config found from the working directory.
+func locateConfig(configPath string) (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	configPath, _ = spec.FindConfig(cwd, configFilename)
+	return configPath, nil
+}
+
+// selectConfig loads the config a command operates on and returns it along with its filename.
+// The project config at configPath, or the one found from the working directory, is used unless --user is set,
+// in which case the user-level config is loaded from the home directory.
+func selectConfig(c *cli.Context, configPath string) (*spec.Config, string, error) {
+	filename, err := locateConfig(configPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.Bool("user") {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return nil, "", report.Coded(report.CodeConfig, fmt.Errorf("Error locating user config: HOME is not set"))
+		}
+		filename, _ = spec.ConfigIn(home, configFilename)
+	}
+
+	config, err := spec.LoadConfig(filename)
+	if err != nil {
+		return nil, "", report.Coded(report.CodeConfig, fmt.Errorf("Error loading config %s: %v", filename, err))
+	}
+	fmt.Fprintf(os.Stderr, "Using config %s\n", displayPath(filename))
+	return &config, filename, nil
+}
+
+// fetchTemplates downloads the named entries of the source in parallel.
+// Entries that cannot be downloaded are reported and left out.
+func fetchTemplates(source spec.Source, names []string) []importer.Template {
+	templates := make([]importer.Template, len(names))
+	failed := make([]error, len(names))
+	limit := make(chan struct{}, fetchWorkers)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			contents, err := network.Fetch(source.GetDownloadLink(spec.Entry{Name: name}))
+			templates[i] = importer.Template{Name: name, Contents: string(contents)}
+			failed[i] = err
+		}(i, name)
+	}
+	wg.Wait()
+
+	var fetched []importer.Template
+	for i, template := range templates {
+		if failed[i] != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping entry %s: %v\n", template.Name, failed[i])
+			continue
+		}
+		fetched = append(fetched, template)
+	}
+	return fetched
+}
+
+// writeImportReport describes the entries found by an import and their confidence.
+func writeImportReport(w io.Writer, source spec.Source, result importer.Result, threshold float64) {
+	if len(result.Matches) == 0 {
+		fmt.Fprintf(w, "No entries from %s matched\n", source)
+	} else {
+		fmt.Fprintf(w, "Added entries from %s:\n", source)
+	}
+	for _, match := range result.Matches {
+		fmt.Fprintf(w, "  %3.0f%%  %s (%d of %d patterns)\n", match.Confidence*100, match.Entry, match.Matched, match.Total)
+	}
+
+	rejected := result.Rejected
+	if len(rejected) > maxRejected {
+		rejected = rejected[:maxRejected]
+	}
+	if len(rejected) > 0 {
+		fmt.Fprintf(w, "Closest entries below the %.0f%% threshold or with too few patterns in common:\n", threshold*100)
+	}
+	for _, match := range rejected {
+		fmt.Fprintf(w, "  %3.0f%%  %s (%d of %d patterns)\n", match.Confidence*100, match.Entry, match.Matched, match.Total)
+	}
+
+	fmt.Fprintf(w, "Added %d unmatched pattern(s) to custom\n", len(result.Unmatched))
+}
+
+// appendMissing appends the patterns that are not already in existing, preserving their order.
+func appendMissing(existing, patterns []string) []string {
+	seen := make(map[string]bool)
+	for _, pattern := range existing {
+		seen[pattern] = true
+	}
+	for _, pattern := range patterns {
+		if !seen[pattern] {
+			seen[pattern] = true
+			existing = append(existing, pattern)
+		}
+	}
+	return existing
+}
+
+// selectMatches lists the detected entries on w and returns those to add. Entries named by names are selected
+// without asking, and every entry is selected when yes is set. Otherwise, the answer read from r selects them:
+// an empty answer accepts every entry, a list of entry numbers or names separated by commas or spaces picks
+// some of them, and no declines with an error.
+func selectMatches(matches []detect.Match, names []string, yes bool, r io.Reader, w io.Writer) ([]detect.Match, error) {
+	fmt.Fprintf(w, "Detected entries from %s:\n", defaultRepo)
+	for i, match := range matches {
+		fmt.Fprintf(w, "  %d. %s (%s)\n", i+1, match.Entry, strings.Join(match.Evidence, ", "))
+	}
+	if len(names) > 0 {
+		return pickMatches(matches, names)
+	}
+	if yes {
+		return matches, nil
+	}
+
+	fmt.Fprint(w, "Add these entries? [Y/n, or the numbers or names of the entries to add] ")
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		return nil, fmt.Errorf("Error reading confirmation: %v, pass --yes or the entries to add to skip it", err)
+	}
+	answer = strings.TrimSpace(answer)
+	switch strings.ToLower(answer) {
+	case "", "y", "yes":
+		return matches, nil
+	case "n", "no":
+		return nil, fmt.Errorf("Aborted, %s was not created", configFilename)
+	}
+	return pickMatches(matches, strings.FieldsFunc(answer, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}))
+}
+
+// pickMatches returns the detected entries chosen by their number in the list or by their name, regardless of case,
+// in the order they were detected. Choosing anything that was not detected is an error.
+func pickMatches(matches []detect.Match, choices []string) ([]detect.Match, error) {
+	chosen := make(map[int]bool)
+	for _, choice := range choices {
+		index := -1
+		if n, err := strconv.Atoi(choice); err == nil && n >= 1 && n <= len(matches) {
+			index = n - 1
+		}
+		for i, match := range matches {
+			if strings.EqualFold(match.Entry, choice) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%s is not one of the detected entries, %s was not created", choice, configFilename)
+		}
+		chosen[index] = true
+	}
+
+	var picked []detect.Match
+	for i, match := range matches {
+		if chosen[i] {
+			picked = append(picked, match)
+		}
+	}
+	return picked, nil
+}
+
+// displayPath shortens path to be relative to the working directory when it is inside of it.
+func displayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// configOutputs returns the ignore files to generate from the config, which are the output path of the options when set.
+// Otherwise, these are the outputs declared by the config. Without declared outputs, the project config generates
+// a .gitignore and the user config generates the global excludes file.
+func configOutputs(config spec.Config, options generateOptions) []spec.Output {
+	if options.output != "" {
+		return []spec.Output{{Path: options.output}}
+	}
+	if len(config.Outputs) > 0 {
+		return config.Outputs
+	}
+	if options.user {
+		return []spec.Output{{Target: spec.TargetGlobalExcludes}}
+	}
+	return []spec.Output{{Path: ignoreFilename}}
+}
+
+// resolveOutput turns the path or target of an output into a concrete path, creating its parent directory if needed.
+// It is only meant for outputs about to be written; commands that merely read them use outputPath instead.
+// Relative output paths are relative to dir, the directory containing the config that declared them.
+func resolveOutput(output spec.Output, dir string) (spec.Output, error) {
+	path, err := outputPath(output, dir)
+	if err != nil {
+		return output, err
+	}
+	output.Path = path
+	return output, os.MkdirAll(filepath.Dir(output.Path), 0755)
+}
+
+// outputPath returns the concrete path of the path or target of an output, as resolveOutput does, without creating anything.
+func outputPath(output spec.Output, dir string) (string, error) {
+	if err := output.Validate(); err != nil {
+		return "", err
+	}
+
+	switch output.Target {
+	case spec.TargetInfoExclude:
+		path, err := git.InfoExcludePath(dir)
+		if err != nil {
+			return "", fmt.Errorf("Error locating %s: %v", output.Target, err)
+		}
+		return path, nil
+	case spec.TargetGlobalExcludes:
+		path, err := git.GlobalExcludesPath()
+		if err != nil {
+			return "", fmt.Errorf("Error locating %s: %v", output.Target, err)
+		}
+		return path, nil
+	}
+	if filepath.IsAbs(output.Path) {
+		return filepath.Clean(output.Path), nil
+	}
+	return filepath.Join(dir, output.Path), nil
+}
+
+// checkOutputConflicts rejects configs that write the same output, since generating them in parallel would race.
+// Configs that cannot be loaded are left for generateConfig to report.
+func checkOutputConflicts(filenames []string, options generateOptions) error {
+	writers := make(map[string]string)
+	for _, filename := range filenames {
+		config, err := spec.LoadConfig(filename)
+		if err != nil {
+			continue
+		}
+		expanded, err := config.Expand(filename)
+		if err != nil {
+			continue
+		}
+		for _, output := range configOutputs(expanded, options) {
+			path, err := outputPath(output, filepath.Dir(filename))
+			if err != nil {
+				continue
+			}
+			if other, ok := writers[path]; ok && other == filename {
+				return fmt.Errorf("%s writes %s more than once", displayPath(filename), displayPath(path))
+			} else if ok {
+				return fmt.Errorf("%s and %s both write %s, so they cannot be generated together", displayPath(other), displayPath(filename), displayPath(path))
+			}
+			writers[path] = filename
+		}
+	}
+	return nil
+}
+
+// renameChooser picks the new name of an entry that was removed upstream among its likely renames,
+// or returns an empty name to leave the entry as it is.
+type renameChooser func(section generate.Section, renames []generate.Rename) (string, error)
+
+// generateOptions control how generateConfig writes outputs and checks them against the git index.
+// output replaces the outputs declared by the config with a single path when it is set.
+// resolveRefs fetches entries at the commit their ref points at rather than at the ref itself.
+// rename follows entries removed upstream to their new name, which are only reported when it is nil.
+type generateOptions struct {
+	user         bool
+	checkTracked bool
+	strict       bool
+	resolveRefs  bool
+	output       string
+	rename       renameChooser
+}
+
+// followSameContents picks the likely rename whose contents match those previously generated, if there is one.
+func followSameContents(section generate.Section, renames []generate.Rename) (string, error) {
+	if len(renames) > 0 && renames[0].SameContents {
+		return renames[0].Entry, nil
+	}
+	return "", nil
+}
+
+// newGenerateOptions reads the options of a command from its flags.
+// The output path is resolved against the working directory, rather than the directory of the config.
+func newGenerateOptions(c *cli.Context, outputPath string) (generateOptions, error) {
+	options := generateOptions{
+		user:         c.Bool("user"),
+		checkTracked: c.Bool("check-tracked") || c.Bool("strict"),
+		strict:       c.Bool("strict"),
+		resolveRefs:  c.Bool("resolve-refs"),
+	}
+	if c.Bool("follow-renames") {
+		options.rename = followSameContents
+	}
+	if outputPath != "" {
+		var err error
+		if options.output, err = filepath.Abs(outputPath); err != nil {
+			return options, err
+		}
+	}
+	return options, nil
+}
+
+// generateConfig writes every output of the config loaded from filename.
+// Warnings and tracked file reports are written to w, so concurrent generations can be reported in order.
+func generateConfig(config spec.Config, filename string, options generateOptions, w io.Writer) (report.Generate, error) {
+	expanded, err := config.Expand(filename)
+	if err != nil {
+		return report.NewGenerate(displayPath(filename)), report.Coded(report.CodeConfig, err)
+	}
+	sections, refWarnings, err := resolveSections(expanded, options, w)
+	if err != nil {
+		return report.NewGenerate(displayPath(filename)), report.Coded(report.CodeConfig, err)
+	}
+	renamed, warnings, err := followRenames(&config, expanded, sections, filename, options, w)
+	if err != nil {
+		return report.NewGenerate(displayPath(filename)), report.Coded(report.CodeConfig, err)
+	}
+	if renamed {
+		if expanded, err = config.Expand(filename); err == nil {
+			sections, refWarnings, err = resolveSections(expanded, options, w)
+		}
+		if err != nil {
+			return report.NewGenerate(displayPath(filename)), report.Coded(report.CodeConfig, err)
+		}
+	}
+	result, err := writeOutputs(expanded, sections, filename, options, w)
+	result.Warnings = append(append(refWarnings, warnings...), result.Warnings...)
+	return result, err
+}
+
+// resolveSections fetches every entry of the expanded config, at the commit its ref points at when options.resolveRefs
+// is set. Refs that could not be resolved are written to w and returned as warnings.
+func resolveSections(expanded spec.Config, options generateOptions, w io.Writer) ([]generate.Section, []string, error) {
+	if !options.resolveRefs {
+		sections, err := generate.Resolve(expanded)
+		return sections, nil, err
+	}
+	sections, warnings, err := generate.ResolveCommits(expanded)
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	return sections, warnings, err
+}
+
+// followRenames looks for the likely new names of entries that were generated before but are no longer found upstream,
+// and renames them in the config loaded from filename when options.rename picks one of them.
+// Entries left as they are are reported as warnings, since generate leaves them out of the outputs.
+// It reports whether the config was renamed and saved, in which case it must be resolved again.
+func followRenames(config *spec.Config, expanded spec.Config, sections []generate.Section, filename string, options generateOptions, w io.Writer) (bool, []string, error) {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warning := fmt.Sprintf(format, args...)
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+		warnings = append(warnings, warning)
+	}
+
+	var previous map[string]generate.Section
+	renamed := false
+	for _, section := range sections {
+		if !section.Missing {
+			continue
+		}
+		if previous == nil {
+			// Without an output in gitignore syntax, no entry is known to have been generated before.
+			if entries, err := previousEntries(expanded, filename, options); err == nil {
+				previous = entries
+			} else {
+				previous = make(map[string]generate.Section)
+			}
+		}
+		old, generated := previous[section.Source+"\n"+section.Entry]
+		if !generated {
+			warn("%s was not found upstream and is left out of the outputs", section.Name())
+			continue
+		}
+
+		var source spec.Source
+		for _, candidate := range expanded.Sources {
+			if candidate.String() == section.Source {
+				source = candidate
+			}
+		}
+		entry := *source.GetEntry(section.Entry)
+		renames, err := generate.FindRenames(source, entry, old.Contents)
+		if err != nil {
+			warn("%s was removed upstream, and its renames could not be searched: %v", section.Name(), err)
+			continue
+		}
+		newName := ""
+		if options.rename != nil && len(renames) > 0 {
+			if newName, err = options.rename(section, renames); err != nil {
+				return false, warnings, err
+			}
+		}
+		if newName == "" {
+			warn("%s was removed upstream%s", section.Name(), renameHint(entry.Name, renames))
+			continue
+		}
+
+		local := config.GetSource(source.Provider, source.Repo, source.Ref)
+		if local == nil || local.GetEntry(entry.Name) == nil {
+			warn("%s was renamed upstream to %s, but it is inherited from an included spec that must be changed instead", section.Name(), newName)
+			continue
+		}
+		if err = spec.MoveEntry(local, local, entry.Name, newName, "", ""); err != nil {
+			warn("%s was renamed upstream to %s, but could not be renamed: %v", section.Name(), newName, err)
+			continue
+		}
+		fmt.Fprintf(w, "Renamed %s to %s in %s\n", section.Name(), newName, displayPath(filename))
+		renamed = true
+	}
+
+	if renamed {
+		if err := config.Save(filename); err != nil {
+			return false, warnings, err
+		}
+	}
+	return renamed, warnings, nil
+}
+
+// renameHint suggests how to follow the likely renames of an entry removed upstream.
+func renameHint(name string, renames []generate.Rename) string {
+	if len(renames) == 0 {
+		return ", and no entry with a similar name or the same contents was found"
+	}
+	hints := make([]string, len(renames))
+	for i, rename := range renames {
+		hints[i] = rename.Entry
+		if rename.SameContents {
+			hints[i] += " (same contents)"
+		}
+	}
+	return fmt.Sprintf(", it may have been renamed to %s, run ignoreit mv %s %s to follow it", strings.Join(hints, ", "), name, renames[0].Entry)
+}
+
+// writeOutputs writes the resolved sections of the expanded config loaded from filename to each of its outputs.
+func writeOutputs(expanded spec.Config, sections []generate.Section, filename string, options generateOptions, w io.Writer) (report.Generate, error) {
+	result := report.NewGenerate(displayPath(filename))
+	for _, section := range sections {
+		if section.Entry != "" {
+			result.Entries = append(result.Entries, report.Fetched{
+				Source:  section.Source,
+				Entry:   section.Entry,
+				Fetched: section.Contents != "",
+				Bytes:   len(section.Contents),
+			})
+		}
+	}
+
+	dir := filepath.Dir(filename)
+	for _, output := range configOutputs(expanded, options) {
+		output, err := resolveOutput(output, dir)
+		if err != nil {
+			return result, report.Coded(report.CodeConfig, err)
+		}
+		format, err := generate.FormatFor(output)
+		if err != nil {
+			return result, report.Coded(report.CodeConfig, err)
+		}
+		warnings, err := generate.Write(expanded, sections, output)
+		if err != nil {
+			return result, report.Coded(report.CodeWrite, fmt.Errorf("Error writing %s: %v", output.Path, err))
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(w, "Warning: %s: %s\n", output.Path, warning)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", output.Path, warning))
+		}
+
+		written := report.Output{Path: displayPath(output.Path), Format: format.Name}
+		if info, err := os.Stat(output.Path); err == nil {
+			written.Bytes = info.Size()
+		}
+		result.Outputs = append(result.Outputs, written)
+	}
+
+	if options.checkTracked {
+		tracked, err := checkTracked(dir, sections, options.strict, w)
+		result.Tracked = tracked
+		return result, err
+	}
+	return result, nil
+}
+
+// updateConfig compares the latest contents of every entry of the config loaded from filename with the contents
+// previously generated for it, and writes the outputs again once the changes to each entry have been accepted or not.
+// Entries whose changes are declined, or whose latest contents could not be fetched, keep their previous contents.
+// Entries removed upstream are offered to be renamed first, and a renamed entry is then compared as a new entry.
+func updateConfig(config spec.Config, filename string, options generateOptions, accept func(generate.Section) (bool, error)) error {
+	expanded, err := config.Expand(filename)
+	if err != nil {
+		return err
+	}
+	sections, err := generate.Resolve(expanded)
+	if err != nil {
+		return err
+	}
+	renamed, _, err := followRenames(&config, expanded, sections, filename, options, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if renamed {
+		if expanded, err = config.Expand(filename); err != nil {
+			return err
+		}
+		if sections, err = generate.Resolve(expanded); err != nil {
+			return err
+		}
+	}
+	previous, err := previousEntries(expanded, filename, options)
+	if err != nil {
+		return err
+	}
+
+	changed, accepted := 0, 0
+	for i, section := range sections {
+		if section.Entry == "" {
+			continue
+		}
+		old, generated := previous[section.Source+"\n"+section.Entry]
+		if section.Contents == "" {
+			if generated {
+				fmt.Fprintf(os.Stderr, "Warning: could not fetch %s, keeping its previous contents\n", section.Name())
+				sections[i].Contents, sections[i].Ref = old.Contents, old.Ref
+			}
+			continue
+		}
+
+		lines := diff.Lines(old.Contents, section.Contents)
+		if !diff.Changed(lines) {
+			continue
+		}
+		changed++
+		if generated {
+			fmt.Printf("\n%s\n", section.Name())
+		} else {
+			fmt.Printf("\n%s (new entry)\n", section.Name())
+		}
+		if err = diff.WriteUnified(os.Stdout, lines, diffContext); err != nil {
+			return err
+		}
+
+		ok, err := accept(section)
+		if err != nil {
+			return err
+		}
+		if ok {
+			accepted++
+		} else {
+			sections[i].Contents, sections[i].Ref = old.Contents, old.Ref
+		}
+	}
+
+	if changed == 0 {
+		fmt.Println("Every entry is up to date")
+		return nil
+	}
+	fmt.Printf("\nAccepted changes to %d of %d entries\n", accepted, changed)
+	if accepted == 0 {
+		return nil
+	}
+	_, err = writeOutputs(expanded, sections, filename, options, os.Stderr)
+	return err
+}
+
+// statusConfig compares the config loaded from filename with its outputs, and with upstream when fetch is set.
+func statusConfig(config spec.Config, filename string, options generateOptions, fetch bool) (report.Status, error) {
+	result := report.Status{Config: displayPath(filename), SchemaVersion: config.SchemaVersion, Outputs: []report.OutputStatus{}, Sources: []report.SourceStatus{}}
+	expanded, err := config.Expand(filename)
+	if err != nil {
+		return result, report.Coded(report.CodeConfig, err)
+	}
+	hash, err := generate.SpecHash(expanded)
+	if err != nil {
+		return result, err
+	}
+
+	for _, output := range configOutputs(expanded, options) {
+		path, err := outputPath(output, filepath.Dir(filename))
+		if err != nil {
+			return result, report.Coded(report.CodeConfig, err)
+		}
+		status := report.OutputStatus{Path: displayPath(path), State: report.OutputMissing}
+		if contents, err := ioutil.ReadFile(path); err == nil {
+			recorded, ok := generate.RecordedSpecHash(string(contents))
+			switch {
+			case !ok:
+				status.State = report.OutputUnmanaged
+			case recorded == hash:
+				status.State = report.OutputInSync
+			default:
+				status.State = report.OutputStale
+			}
+		} else if !os.IsNotExist(err) {
+			return result, err
+		}
+		result.Outputs = append(result.Outputs, status)
+	}
+
+	// Entries are only known to be generated when an output in gitignore syntax can be parsed.
+	previous, err := previousEntries(expanded, filename, options)
+	if err != nil {
+		previous = nil
+	}
+	latest := make(map[string]generate.Section)
+	if fetch {
+		sections, err := generate.Resolve(expanded)
+		if err != nil {
+			return result, report.Coded(report.CodeConfig, err)
+		}
+		for _, section := range sections {
+			if section.Entry != "" {
+				latest[section.Source+"\n"+section.Entry] = section
+			}
+		}
+	}
+
+	for _, source := range expanded.Sources {
+		sourceStatus := report.SourceStatus{Name: source.String(), Provider: source.Provider, Repo: source.Repo, Ref: source.Ref, Entries: []report.EntryStatus{}}
+		for _, entry := range source.Entries {
+			key := source.String() + "\n" + entry.Name
+			old, generated := previous[key]
+			status := report.EntryStatus{
+				Name:         entry.Name,
+				Ref:          source.Ref,
+				Pinned:       entry.Ref != "" || spec.IsCommit(source.Ref),
+				Generated:    generated,
+				GeneratedRef: old.Ref,
+				Upstream:     report.UpstreamUnchecked,
+			}
+			if entry.Ref != "" {
+				status.Ref = entry.Ref
+			}
+
+			if section, ok := latest[key]; ok {
+				switch {
+				case section.Missing:
+					status.Upstream = report.UpstreamRemoved
+				case section.Contents == "":
+					status.Upstream = report.UpstreamUnavailable
+				case !generated:
+					status.Upstream = report.UpstreamNotGenerated
+				case diff.Changed(diff.Lines(old.Contents, section.Contents)):
+					status.Upstream = report.UpstreamChanged
+				default:
+					status.Upstream = report.UpstreamUnchanged
+				}
+			}
+			sourceStatus.Entries = append(sourceStatus.Entries, status)
+		}
+		result.Sources = append(result.Sources, sourceStatus)
+	}
+	return result, nil
+}
+
+// previousEntries parses the entry sections of the first output of the config written in gitignore syntax,
+// keyed by their source and entry. Outputs that were not generated yet have no entries.
+func previousEntries(config spec.Config, filename string, options generateOptions) (map[string]generate.Section, error) {
+	sections, err := generatedEntries(config, filename, options)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]generate.Section)
+	for _, section := range sections {
+		entries[section.Source+"\n"+section.Entry] = section
+	}
+	return entries, nil
+}
+
+// generatedEntries parses the entry sections of the first output of the config written in gitignore syntax, in output order.
+func generatedEntries(config spec.Config, filename string, options generateOptions) ([]generate.Section, error) {
+	for _, output := range configOutputs(config, options) {
+		format, err := generate.FormatFor(output)
+		if err != nil {
+			return nil, err
+		}
+		if !format.Verbatim() {
+			continue
+		}
+
+		path, err := outputPath(output, filepath.Dir(filename))
+		if err != nil {
+			return nil, err
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		return generate.ParseEntries(string(contents)), nil
+	}
+	return nil, fmt.Errorf("Error comparing entries: %s has no output in gitignore syntax", displayPath(filename))
+}
+
+// generateRecursive generates every config found under the root of the repository, or the working directory
+// outside of a repository. Configs are generated in parallel, but their logs are written in path order,
+// and an error is returned if any of them failed.
+func generateRecursive(options generateOptions) (report.GenerateAll, error) {
+	result := report.GenerateAll{Configs: []report.Generate{}}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return result, err
+	}
+	root, err := git.Root(cwd)
+	if err != nil {
+		root = cwd
+	}
+	filenames, err := spec.FindConfigs(root, configFilename)
+	if err != nil {
+		return result, report.Coded(report.CodeConfig, fmt.Errorf("Error discovering configs: %v", err))
+	}
+	if err = checkOutputConflicts(filenames, options); err != nil {
+		return result, report.Coded(report.CodeConfig, err)
+	}
+
+	logs := make([]bytes.Buffer, len(filenames))
+	result.Configs = make([]report.Generate, len(filenames))
+	limit := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, filename := range filenames {
+		wg.Add(1)
+		go func(i int, filename string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			generated := report.NewGenerate(displayPath(filename))
+			config, err := spec.LoadConfig(filename)
+			if err != nil {
+				err = report.Coded(report.CodeConfig, err)
+			} else {
+				generated, err = generateConfig(config, filename, options, &logs[i])
+			}
+			if err != nil {
+				generated.Error = report.Coded(report.CodeUnknown, err).(*report.Error)
+			}
+			result.Configs[i] = generated
+		}(i, filename)
+	}
+	wg.Wait()
+
+	for i := range filenames {
+		os.Stderr.Write(logs[i].Bytes())
+		if result.Configs[i].Error == nil {
+			result.Generated++
+		}
+	}
+
+	if failed := len(filenames) - result.Generated; failed > 0 {
+		return result, report.Coded(report.CodeGenerate, fmt.Errorf("%d of %d config(s) failed to generate", failed, len(filenames)))
+	}
+	return result, nil
+}
+
+// checkTracked reports the files tracked under dir that the generated sections would ignore, and returns them.
+// With strict set, an error is returned when there are any.
+func checkTracked(dir string, sections []generate.Section, strict bool, w io.Writer) ([]report.Tracked, error) {
+	tracked := []report.Tracked{}
+	files, err := git.TrackedFiles(dir)
+	if err != nil {
+		return tracked, fmt.Errorf("Error listing tracked files: %v", err)
+	}
+
+	for _, group := range generate.CheckTracked(sections, files) {
+		fmt.Fprintf(w, "Tracked files ignored by %s:\n", group.Section)
+		for _, file := range group.Files {
+			fmt.Fprintf(w, "  %s (matched by %q)\n", filepath.Join(dir, file.Path), file.Pattern)
+			tracked = append(tracked, report.Tracked{Path: displayPath(filepath.Join(dir, file.Path)), Pattern: file.Pattern, Section: group.Section})
+		}
+	}
+
+	if len(tracked) > 0 && strict {
+		return tracked, report.Coded(report.CodeTrackedIgnored, fmt.Errorf("%d tracked file(s) under %s would be ignored by the generated patterns", len(tracked), dir))
+	}
+	return tracked, nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/detect"
+	"github.com/whoshuu/ignoreit/report"
+	"github.com/whoshuu/ignoreit/spec"
+)
+
+const raw = "https://raw.githubusercontent.com/github/gitignore/master/"
+
+// stubTransport serves canned bodies by URL, answering 404 for anything else, unless statuses says otherwise.
+type stubTransport struct {
+	bodies   map[string]string
+	statuses map[string]int
+}
+
+func (stub *stubTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	url := request.URL.String()
+	status, body := http.StatusNotFound, ""
+	if contents, ok := stub.bodies[url]; ok {
+		status, body = http.StatusOK, contents
+	}
+	if code, ok := stub.statuses[url]; ok {
+		status = code
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    request,
+	}, nil
+}
+
+// stubProviders replaces the HTTP transport with stub until the returned function is called.
+func stubProviders(stub *stubTransport) func() {
+	transport := http.DefaultTransport
+	http.DefaultTransport = stub
+	return func() {
+		http.DefaultTransport = transport
+	}
+}
+
+// writeConfig writes contents to a spec in a new directory under dir and loads it.
+func writeConfig(t *testing.T, dir, name, contents string) (spec.Config, string) {
+	filename := filepath.Join(dir, name, configFilename)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := spec.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("Spec %s should load, got %v instead", filename, err)
+	}
+	return config, filename
+}
+
+func TestSelectMatches(t *testing.T) {
+	matches := []detect.Match{
+		{Entry: "Go", Evidence: []string{"go.mod"}},
+		{Entry: "Node", Evidence: []string{"package.json"}},
+		{Entry: "Rust", Evidence: []string{"Cargo.toml"}},
+	}
+	tests := []struct {
+		answer   string
+		names    []string
+		yes      bool
+		expected []string
+		err      string
+	}{
+		{answer: "\n", expected: []string{"Go", "Node", "Rust"}},
+		{answer: "Y\n", expected: []string{"Go", "Node", "Rust"}},
+		{answer: "", yes: true, expected: []string{"Go", "Node", "Rust"}},
+		{answer: "no\n", err: "Aborted"},
+		{answer: "", err: "Error reading confirmation"},
+		{answer: "3\n", expected: []string{"Rust"}},
+		{answer: "rust, 1\n", expected: []string{"Go", "Rust"}},
+		{answer: " 2 go 2\n", expected: []string{"Go", "Node"}},
+		{answer: "4\n", err: "4 is not one of the detected entries"},
+		{answer: "Python\n", err: "Python is not one of the detected entries"},
+		{answer: "", names: []string{"node"}, expected: []string{"Node"}},
+		{answer: "", names: []string{"Node", "C"}, yes: true, err: "C is not one of the detected entries"},
+	}
+	for _, test := range tests {
+		selected, err := selectMatches(matches, test.names, test.yes, strings.NewReader(test.answer), ioutil.Discard)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Answer %q with entries %v should fail with %q, got %v instead", test.answer, test.names, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Answer %q with entries %v should not fail, got %v instead", test.answer, test.names, err)
+			continue
+		}
+		var entries []string
+		for _, match := range selected {
+			entries = append(entries, match.Entry)
+		}
+		if !reflect.DeepEqual(entries, test.expected) {
+			t.Errorf("Answer %q with entries %v should select %v, got %v instead", test.answer, test.names, test.expected, entries)
+		}
+	}
+}
+
+func TestCheckOutputConflicts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, app := writeConfig(t, dir, "app", "schema_version: 2\noutputs:\n- path: ../shared/.gitignore\n")
+	_, lib := writeConfig(t, dir, "lib", "schema_version: 2\n")
+	_, tool := writeConfig(t, dir, "tool", "schema_version: 2\noutputs:\n- path: ../shared/.gitignore\n")
+	_, twice := writeConfig(t, dir, "twice", "schema_version: 2\noutputs:\n- path: .gitignore\n- path: ./.gitignore\n")
+
+	tests := []struct {
+		filenames []string
+		options   generateOptions
+		err       string
+	}{
+		{filenames: []string{app, lib}},
+		{filenames: []string{app, lib, tool}, err: "both write"},
+		{filenames: []string{twice}, err: "more than once"},
+		{filenames: []string{app, lib}, options: generateOptions{output: filepath.Join(dir, ".gitignore")}, err: "both write"},
+	}
+	for _, test := range tests {
+		err := checkOutputConflicts(test.filenames, test.options)
+		if test.err == "" && err != nil {
+			t.Errorf("Specs %v should not conflict, got %v instead", test.filenames, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("Specs %v should conflict with %q, got %v instead", test.filenames, test.err, err)
+		}
+	}
+}
+
+func TestStatusConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stub := &stubTransport{bodies: map[string]string{
+		raw + "Go.gitignore":   "*.exe\n",
+		raw + "Gone.gitignore": "gone/\n",
+	}}
+	defer stubProviders(stub)()
+
+	const sources = "schema_version: 2\nsources:\n- provider: github\n  repo: github/gitignore\n  ref: master\n  entries:\n  - Go\n  - Gone\n"
+	config, filename := writeConfig(t, dir, "project", sources)
+	output := filepath.Join(filepath.Dir(filename), ignoreFilename)
+	outputState := func(step string) string {
+		status, err := statusConfig(config, filename, generateOptions{}, false)
+		if err != nil {
+			t.Fatalf("Status %s should not fail, got %v instead", step, err)
+		}
+		if len(status.Outputs) != 1 {
+			t.Fatalf("Status %s should have one output, got %v instead", step, status.Outputs)
+		}
+		return status.Outputs[0].State
+	}
+
+	if state := outputState("before generating"); state != report.OutputMissing {
+		t.Errorf("Output state before generating should be %s, got %s instead", report.OutputMissing, state)
+	}
+	if err = ioutil.WriteFile(output, []byte("*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if state := outputState("of a hand written output"); state != report.OutputUnmanaged {
+		t.Errorf("Output state of a hand written output should be %s, got %s instead", report.OutputUnmanaged, state)
+	}
+	if _, err = generateConfig(config, filename, generateOptions{}, ioutil.Discard); err != nil {
+		t.Fatalf("Generate should not fail, got %v instead", err)
+	}
+	if state := outputState("after generating"); state != report.OutputInSync {
+		t.Errorf("Output state after generating should be %s, got %s instead", report.OutputInSync, state)
+	}
+
+	stub.bodies[raw+"Go.gitignore"] = "*.exe\n*.test\n"
+	stub.bodies[raw+"Rust.gitignore"] = "target/\n"
+	delete(stub.bodies, raw+"Gone.gitignore")
+	stub.statuses = map[string]int{raw + "C.gitignore": http.StatusInternalServerError}
+	config, filename = writeConfig(t, dir, "project", sources+"  - Rust\n  - C\n")
+	if state := outputState("after changing the spec"); state != report.OutputStale {
+		t.Errorf("Output state after changing the spec should be %s, got %s instead", report.OutputStale, state)
+	}
+
+	status, err := statusConfig(config, filename, generateOptions{}, true)
+	if err != nil {
+		t.Fatalf("Status should not fail, got %v instead", err)
+	}
+	expected := map[string]string{
+		"Go":   report.UpstreamChanged,
+		"Gone": report.UpstreamRemoved,
+		"Rust": report.UpstreamNotGenerated,
+		"C":    report.UpstreamUnavailable,
+	}
+	for _, entry := range status.Sources[0].Entries {
+		if entry.Upstream != expected[entry.Name] {
+			t.Errorf("Upstream state of %s should be %s, got %s instead", entry.Name, expected[entry.Name], entry.Upstream)
+		}
+	}
+
+	stub.bodies[raw+"Gone.gitignore"] = "gone/\n"
+	status, err = statusConfig(config, filename, generateOptions{}, true)
+	if err != nil {
+		t.Fatalf("Status should not fail, got %v instead", err)
+	}
+	if entry := status.Sources[0].Entries[1]; entry.Name != "Gone" || entry.Upstream != report.UpstreamUnchanged {
+		t.Errorf("Upstream state of Gone should be %s, got %s instead", report.UpstreamUnchanged, entry.Upstream)
+	}
+}
+
+func TestFollowRenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stub := &stubTransport{bodies: map[string]string{
+		raw + "Global/OSX.gitignore": ".DS_Store\n",
+	}}
+	defer stubProviders(stub)()
+
+	const sources = "schema_version: 2\nsources:\n- provider: github\n  repo: github/gitignore\n  ref: master\n  entries:\n  - Global/OSX\n"
+	tests := []struct {
+		name     string
+		options  generateOptions
+		warnings int
+		entry    string
+	}{
+		{"warned", generateOptions{}, 1, "Global/OSX"},
+		{"followed", generateOptions{rename: followSameContents}, 0, "Global/macOS"},
+	}
+	for _, test := range tests {
+		stub.bodies[raw+"Global/OSX.gitignore"] = ".DS_Store\n"
+		config, filename := writeConfig(t, dir, test.name, sources)
+		if _, err = generateConfig(config, filename, generateOptions{}, ioutil.Discard); err != nil {
+			t.Fatalf("Generate should not fail, got %v instead", err)
+		}
+
+		delete(stub.bodies, raw+"Global/OSX.gitignore")
+		stub.bodies[raw+"Global/macOS.gitignore"] = ".DS_Store\n"
+		stub.bodies["https://api.github.com/repos/github/gitignore/git/trees/master?recursive=1"] = `{"tree": [{"path": "Global/macOS.gitignore", "type": "blob"}]}`
+		result, err := generateConfig(config, filename, test.options, ioutil.Discard)
+		if err != nil {
+			t.Fatalf("Generate should not fail, got %v instead", err)
+		}
+		if len(result.Warnings) != test.warnings {
+			t.Errorf("Generate %s should return %d warnings, got %v instead", test.name, test.warnings, result.Warnings)
+		}
+		if test.warnings > 0 && !strings.Contains(result.Warnings[0], "Global/macOS (same contents)") {
+			t.Errorf("Generate %s should warn about the likely rename, got %v instead", test.name, result.Warnings)
+		}
+
+		renamed, err := spec.LoadConfig(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entries := renamed.Sources[0].Entries; len(entries) != 1 || entries[0].Name != test.entry {
+			t.Errorf("Entries %s should be [%s], got %v instead", test.name, test.entry, entries)
+		}
+	}
+}
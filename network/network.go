@@ -4,16 +4,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
 )
 
 // EntryExists checks if the input url points to a valid hosted .gitignore file.
 // An HTTP request with method HEAD expects to return 200 OK in the response.
 // Any other response is interpreted to mean that the .gitignore entry does not exist.
+// Request errors are reported on stderr, keeping stdout free for the output of commands.
 func EntryExists(url string) bool {
 	resp, err := http.Head(url)
 
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		return false
 	}
 
@@ -23,23 +25,41 @@ func EntryExists(url string) bool {
 // EntryContents gets the contents of the .gitignore file pointed to be the input url.
 // If the response is not 200 OK, an empty string is returned instead.
 func EntryContents(url string) string {
-	resp, err := http.Get(url)
+	body, err := Fetch(url)
 
 	if err != nil {
-		fmt.Println(err)
+		if _, ok := err.(StatusError); !ok {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		return ""
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return ""
-	}
+	return string(body)
+}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+// StatusError is returned by Fetch when the server responds with anything other than 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (err StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", err.URL, err.StatusCode, http.StatusText(err.StatusCode))
+}
+
+// Fetch downloads the resource pointed to by the input url.
+// Any response other than 200 OK is reported as a StatusError.
+func Fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
 
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
 
-	return string(body)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, StatusError{url, resp.StatusCode}
+	}
+
+	return ioutil.ReadAll(resp.Body)
 }
@@ -0,0 +1,225 @@
+package pattern
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a single .gitignore pattern compiled for matching against slash separated paths.
+// Paths are always interpreted relative to the directory containing the ignore file.
+// Origin is an optional label describing where the pattern came from, such as the entry it was fetched for.
+type Pattern struct {
+	Text     string
+	Origin   string
+	Negate   bool
+	DirOnly  bool
+	Anchored bool
+	re       *regexp.Regexp
+}
+
+// Parse compiles a single line of a .gitignore file.
+// Blank lines and comments are not patterns, so nil is returned for them without an error.
+func Parse(line string) (*Pattern, error) {
+	text := TrimTrailingSpace(strings.TrimSuffix(line, "\r"))
+	if text == "" || strings.HasPrefix(text, "#") {
+		return nil, nil
+	}
+
+	p := &Pattern{Text: text}
+	body := text
+	if strings.HasPrefix(body, "!") {
+		p.Negate = true
+		body = body[1:]
+	}
+	if strings.HasSuffix(body, "/") && !strings.HasSuffix(body, "\\/") {
+		p.DirOnly = true
+		body = strings.TrimSuffix(body, "/")
+	}
+	if body == "" {
+		return nil, fmt.Errorf("pattern %q does not match any path", text)
+	}
+
+	p.Anchored = strings.Contains(body, "/")
+	body = strings.TrimPrefix(body, "/")
+
+	expr, err := translate(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %s", text, err)
+	}
+	if p.Anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	if p.re, err = regexp.Compile(expr); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %s", text, err)
+	}
+	return p, nil
+}
+
+// ParseLines compiles every pattern in lines, skipping blank lines and comments.
+// Each returned pattern has its Origin set to the input origin.
+func ParseLines(lines []string, origin string) ([]*Pattern, error) {
+	var patterns []*Pattern
+	for _, line := range lines {
+		p, err := Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			p.Origin = origin
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, nil
+}
+
+// Match reports whether the pattern matches path on its own, ignoring negation and parent directories.
+// Use a Matcher to evaluate a whole ignore file the way git does.
+func (p Pattern) Match(path string, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	return p.re.MatchString(path)
+}
+
+// Regexp returns the anchored regular expression the pattern was compiled to.
+func (p Pattern) Regexp() string {
+	return p.re.String()
+}
+
+// Matcher evaluates an ordered list of patterns the way git does, where later patterns override earlier ones.
+type Matcher []*Pattern
+
+// Match returns the pattern that decides whether path is ignored, and whether it is ignored.
+// Parent directories are checked first since git cannot re-include a file whose parent directory is excluded.
+// If no pattern matches, nil and false are returned.
+func (m Matcher) Match(path string, isDir bool) (*Pattern, bool) {
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if p := m.last(strings.Join(parts[:i], "/"), true); p != nil && !p.Negate {
+			return p, true
+		}
+	}
+
+	p := m.last(path, isDir)
+	return p, p != nil && !p.Negate
+}
+
+func (m Matcher) last(path string, isDir bool) *Pattern {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].Match(path, isDir) {
+			return m[i]
+		}
+	}
+	return nil
+}
+
+// TrimTrailingSpace removes the trailing spaces of a line that git ignores, keeping a space escaped with a backslash.
+// A backslash that is escaped itself does not escape the space after it. Tabs are left alone,
+// since git treats them as part of the pattern.
+func TrimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") {
+		backslashes := 0
+		for i := len(line) - 2; i >= 0 && line[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			break
+		}
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// translate converts the glob syntax of a .gitignore pattern body into a regular expression.
+// The body must already have its negation, trailing slash and leading slash removed.
+func translate(body string) (string, error) {
+	var expr bytes.Buffer
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '*' && strings.HasPrefix(body[i:], "**"):
+			atStart := i == 0 || body[i-1] == '/'
+			atEnd := i+2 == len(body) || body[i+2] == '/'
+			switch {
+			case atStart && atEnd && i+2 == len(body):
+				expr.WriteString(".*")
+			case atStart && atEnd:
+				expr.WriteString("(?:.*/)?")
+				i++
+			default:
+				expr.WriteString("[^/]*")
+			}
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := classEnd(body, i)
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			expr.WriteString(translateClass(body[i+1 : end]))
+			i = end
+		case c == '\\':
+			if i+1 == len(body) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(body[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(body[i : i+1]))
+		}
+	}
+	return expr.String(), nil
+}
+
+// classEnd finds the index of the bracket closing the character class opened at start, or -1 if it is unterminated.
+func classEnd(body string, start int) int {
+	i := start + 1
+	if i < len(body) && (body[i] == '!' || body[i] == '^') {
+		i++
+	}
+	if i < len(body) && body[i] == ']' {
+		i++
+	}
+	for ; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func translateClass(class string) string {
+	var expr bytes.Buffer
+	expr.WriteString("[")
+	if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+		expr.WriteString("^/")
+		class = class[1:]
+	}
+	for i := 0; i < len(class); i++ {
+		switch c := class[i]; c {
+		case '\\':
+			if i+1 < len(class) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(class[i : i+1]))
+			}
+		case '[', ']', '^':
+			expr.WriteString(`\` + string(c))
+		default:
+			expr.WriteByte(c)
+		}
+	}
+	expr.WriteString("]")
+	return expr.String()
+}
@@ -0,0 +1,110 @@
+package pattern
+
+import (
+	"testing"
+)
+
+func TestParseSkipsBlankAndComments(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "\r"} {
+		p, err := Parse(line)
+		if p != nil || err != nil {
+			t.Errorf("Line %q should not be a pattern, got %v, %v instead", line, p, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{"!", "/", "foo\\"} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Line %q should fail to parse", line)
+		}
+	}
+}
+
+func TestTrimTrailingSpace(t *testing.T) {
+	tests := map[string]string{
+		"*.log   ": "*.log",
+		"a\\ ":     "a\\ ",
+		"a\\  ":    "a\\ ",
+		"a\\\\ ":   "a\\\\",
+		"a\\\\\\ ": "a\\\\\\ ",
+		"tab\t":    "tab\t",
+		"   ":      "",
+	}
+	for line, expected := range tests {
+		if actual := TrimTrailingSpace(line); actual != expected {
+			t.Errorf("Trimmed %q should be %q, got %q instead", line, expected, actual)
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.o", "main.o", false, true},
+		{"*.o", "build/main.o", false, true},
+		{"*.o", "main.c", false, false},
+		{"/vendor", "vendor", true, true},
+		{"/vendor", "src/vendor", true, false},
+		{"vendor/", "src/vendor", true, true},
+		{"vendor/", "src/vendor", false, false},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/arch.txt", false, false},
+		{"**/logs", "deep/down/logs", true, true},
+		{"logs/**", "logs/a/b.log", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"debug[0-9].log", "debug7.log", false, true},
+		{"debug[!0-9].log", "debug7.log", false, false},
+		{"\\#notes", "#notes", false, true},
+		{"file?.txt", "file1.txt", false, true},
+		{"file?.txt", "dir/file12.txt", false, false},
+	}
+
+	for _, c := range cases {
+		p, err := Parse(c.pattern)
+		if err != nil {
+			t.Fatalf("Pattern %q should parse: %s", c.pattern, err)
+		}
+		if p.Match(c.path, c.isDir) != c.match {
+			t.Errorf("Pattern %q matching %q (dir: %t) should be %t", c.pattern, c.path, c.isDir, c.match)
+		}
+	}
+}
+
+func TestMatcherNegation(t *testing.T) {
+	patterns, err := ParseLines([]string{"*.log", "!keep.log", "build/", "!build/keep.txt"}, "test")
+	if err != nil {
+		t.Fatalf("Patterns should parse: %s", err)
+	}
+	matcher := Matcher(patterns)
+
+	cases := []struct {
+		path    string
+		ignored bool
+		decider string
+	}{
+		{"error.log", true, "*.log"},
+		{"keep.log", false, "!keep.log"},
+		{"build/out.bin", true, "build/"},
+		{"build/keep.txt", true, "build/"},
+		{"src/main.go", false, ""},
+	}
+
+	for _, c := range cases {
+		p, ignored := matcher.Match(c.path, false)
+		if ignored != c.ignored {
+			t.Errorf("Path %q should have ignored: %t, got %t instead", c.path, c.ignored, ignored)
+		}
+		if (p == nil && c.decider != "") || (p != nil && p.Text != c.decider) {
+			t.Errorf("Path %q should be decided by %q, got %v instead", c.path, c.decider, p)
+		}
+		if p != nil && p.Origin != "test" {
+			t.Errorf("Pattern origin should be test, got %s instead", p.Origin)
+		}
+	}
+}
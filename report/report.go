@@ -0,0 +1,457 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Version is the version of the JSON documents written by WriteJSON.
+// It is only incremented when a field is removed or changes meaning, never when one is added.
+const Version = 1
+
+// Error codes reported in JSON documents, which stay stable across releases even when messages change.
+const (
+	// CodeUsage means the command was invoked with invalid flags or arguments.
+	CodeUsage = "usage"
+	// CodeConfig means the spec, or a spec it includes, could not be located, loaded or saved.
+	CodeConfig = "config"
+	// CodeWrite means an output file could not be written.
+	CodeWrite = "write"
+	// CodeTrackedIgnored means --strict found tracked files that the generated patterns ignore.
+	CodeTrackedIgnored = "tracked_ignored"
+	// CodeGenerate means one or more specs failed to generate with --recursive.
+	CodeGenerate = "generate"
+	// CodeUnknown is reported for errors without a more specific code.
+	CodeUnknown = "unknown"
+)
+
+// Error is an error with a stable code, reported in the error field of JSON documents.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// Coded attaches a code to err, unless it is nil or already has one.
+func Coded(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{code, err.Error()}
+}
+
+// document is the envelope of every JSON document, holding the result of the command or the error it failed with.
+// Commands that fail part way, like generating several specs, report both.
+type document struct {
+	Version int         `json:"version"`
+	Command string      `json:"command"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+}
+
+// WriteJSON writes the result or error of command as an indented JSON document.
+func WriteJSON(w io.Writer, command string, result interface{}, err error) error {
+	doc := document{Version: Version, Command: command, Result: result}
+	if err != nil {
+		coded, ok := Coded(CodeUnknown, err).(*Error)
+		if !ok {
+			coded = &Error{CodeUnknown, err.Error()}
+		}
+		doc.Error = coded
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// TextWriter is implemented by results with a human readable form.
+type TextWriter interface {
+	WriteText(w io.Writer) error
+}
+
+// Skipped is an entry that a command did not change, along with the reason why.
+type Skipped struct {
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// Add is the result of adding entries to a source of a spec.
+type Add struct {
+	Config  string    `json:"config"`
+	Source  string    `json:"source"`
+	Added   []string  `json:"added"`
+	Skipped []Skipped `json:"skipped"`
+}
+
+// WriteText lists the skipped entries, since added entries need no explanation.
+func (result Add) WriteText(w io.Writer) error {
+	return writeSkipped(w, result.Skipped)
+}
+
+// Remove is the result of removing entries from a source of a spec.
+type Remove struct {
+	Config  string    `json:"config"`
+	Source  string    `json:"source"`
+	Removed []string  `json:"removed"`
+	Skipped []Skipped `json:"skipped"`
+}
+
+// WriteText lists the skipped entries, since removed entries need no explanation.
+func (result Remove) WriteText(w io.Writer) error {
+	return writeSkipped(w, result.Skipped)
+}
+
+func writeSkipped(w io.Writer, skipped []Skipped) error {
+	for _, skip := range skipped {
+		if _, err := fmt.Fprintf(w, "Skipped %s: %s\n", skip.Entry, skip.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fetched is an entry whose contents were downloaded while generating. Bytes is the size of the downloaded
+// contents after applying the entry options, which is zero when the download failed.
+type Fetched struct {
+	Source  string `json:"source"`
+	Entry   string `json:"entry"`
+	Fetched bool   `json:"fetched"`
+	Bytes   int    `json:"bytes"`
+}
+
+// Output is an ignore file written while generating.
+type Output struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// Tracked is a file in the git index that the generated patterns ignore.
+type Tracked struct {
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+	Section string `json:"section"`
+}
+
+// Generate is the result of generating the outputs of a spec.
+type Generate struct {
+	Config   string    `json:"config"`
+	Entries  []Fetched `json:"entries"`
+	Outputs  []Output  `json:"outputs"`
+	Warnings []string  `json:"warnings"`
+	Tracked  []Tracked `json:"tracked"`
+	Error    *Error    `json:"error,omitempty"`
+}
+
+// NewGenerate returns an empty result for generating the spec at config, whose lists are empty rather than null in JSON.
+func NewGenerate(config string) Generate {
+	return Generate{Config: config, Entries: []Fetched{}, Outputs: []Output{}, Warnings: []string{}, Tracked: []Tracked{}}
+}
+
+// WriteText writes nothing, since warnings and tracked files are reported while generating.
+func (result Generate) WriteText(w io.Writer) error {
+	return nil
+}
+
+// GenerateAll is the result of generating every spec under the repository root.
+// Each spec reports its own error, if it failed to generate.
+type GenerateAll struct {
+	Configs   []Generate `json:"configs"`
+	Generated int        `json:"generated"`
+}
+
+// WriteText lists whether each spec was generated, followed by a summary.
+func (result GenerateAll) WriteText(w io.Writer) error {
+	for _, config := range result.Configs {
+		var err error
+		if config.Error != nil {
+			_, err = fmt.Fprintf(w, "FAIL %s: %s\n", config.Config, config.Error.Message)
+		} else {
+			_, err = fmt.Fprintf(w, "ok   %s\n", config.Config)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Generated %d of %d config(s)\n", result.Generated, len(result.Configs))
+	return err
+}
+
+// Entry is an entry of a source, along with its options.
+type Entry struct {
+	Name    string   `json:"name"`
+	Ref     string   `json:"ref,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Append  []string `json:"append,omitempty"`
+}
+
+// Source is a source of a spec and its entries. Name describes the source the way it appears in generated files.
+type Source struct {
+	Name     string  `json:"name"`
+	Provider string  `json:"provider"`
+	Repo     string  `json:"repo"`
+	Ref      string  `json:"ref"`
+	Entries  []Entry `json:"entries"`
+}
+
+// Group is a named group of custom patterns.
+type Group struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+}
+
+// List is the result of listing the entries of a spec, merged with the specs it includes.
+type List struct {
+	Config  string   `json:"config"`
+	Sources []Source `json:"sources"`
+	Groups  []Group  `json:"groups"`
+	Custom  []string `json:"custom"`
+}
+
+// WriteText lists the entries under their source, followed by the custom patterns.
+func (result List) WriteText(w io.Writer) error {
+	var lines []string
+	for _, source := range result.Sources {
+		lines = append(lines, source.Name)
+		for _, entry := range source.Entries {
+			var options []string
+			if entry.Ref != "" {
+				options = append(options, "ref "+entry.Ref)
+			}
+			if len(entry.Exclude) > 0 {
+				options = append(options, fmt.Sprintf("%d excluded", len(entry.Exclude)))
+			}
+			if len(entry.Append) > 0 {
+				options = append(options, fmt.Sprintf("%d appended", len(entry.Append)))
+			}
+			if len(options) > 0 {
+				lines = append(lines, fmt.Sprintf("  %s (%s)", entry.Name, strings.Join(options, ", ")))
+			} else {
+				lines = append(lines, "  "+entry.Name)
+			}
+		}
+	}
+	for _, group := range result.Groups {
+		lines = append(lines, fmt.Sprintf("Custom: %s (%d patterns)", group.Name, len(group.Patterns)))
+	}
+	if len(result.Custom) > 0 {
+		lines = append(lines, fmt.Sprintf("Custom Patterns (%d patterns)", len(result.Custom)))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Explanation is the pattern deciding whether a path is ignored. Pattern and Section are empty when no pattern matches,
+// and a matching negated pattern means the path is explicitly not ignored.
+type Explanation struct {
+	Path    string `json:"path"`
+	Ignored bool   `json:"ignored"`
+	Pattern string `json:"pattern,omitempty"`
+	Section string `json:"section,omitempty"`
+}
+
+// Explain is the result of explaining why paths are or are not ignored.
+type Explain struct {
+	Config string        `json:"config"`
+	Paths  []Explanation `json:"paths"`
+}
+
+// WriteText describes the pattern deciding each path.
+func (result Explain) WriteText(w io.Writer) error {
+	for _, path := range result.Paths {
+		var err error
+		switch {
+		case path.Pattern == "":
+			_, err = fmt.Fprintf(w, "%s: not ignored\n", path.Path)
+		case path.Ignored:
+			_, err = fmt.Fprintf(w, "%s: ignored by %q from %s\n", path.Path, path.Pattern, path.Section)
+		default:
+			_, err = fmt.Fprintf(w, "%s: not ignored, re-included by %q from %s\n", path.Path, path.Pattern, path.Section)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LintPattern is the number of paths a single pattern matches on its own.
+type LintPattern struct {
+	Pattern string `json:"pattern"`
+	Hits    int    `json:"hits"`
+}
+
+// LintSection holds the hit counts for the patterns of a single section.
+// Hits is the number of paths matched by at least one pattern of the section.
+// Dead lists the patterns that match nothing in the tree, in the order they appear in the section.
+type LintSection struct {
+	Section  string        `json:"section"`
+	Hits     int           `json:"hits"`
+	Patterns []LintPattern `json:"patterns"`
+	Dead     []string      `json:"dead"`
+}
+
+// Lint is the result of evaluating the generated patterns of each section against a project tree.
+type Lint struct {
+	Config   string        `json:"config"`
+	Root     string        `json:"root"`
+	Paths    int           `json:"paths"`
+	Sections []LintSection `json:"sections"`
+}
+
+// WriteText lists the dead patterns of every section.
+func (result Lint) WriteText(w io.Writer) error {
+	for _, section := range result.Sections {
+		_, err := fmt.Fprintf(w, "%s: %d path(s) matched, %d of %d pattern(s) never match\n",
+			section.Section, section.Hits, len(section.Dead), len(section.Patterns))
+		if err != nil {
+			return err
+		}
+		for _, dead := range section.Dead {
+			if _, err = fmt.Fprintf(w, "  %s\n", dead); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// States of an output in a Status, comparing the generated file with the spec.
+const (
+	// OutputInSync means the output was generated from the current spec.
+	OutputInSync = "in_sync"
+	// OutputStale means the spec changed since the output was generated.
+	OutputStale = "stale"
+	// OutputMissing means the output was never generated.
+	OutputMissing = "missing"
+	// OutputUnmanaged means the output has no recorded spec hash, being written by hand or by an older ignoreit.
+	OutputUnmanaged = "unmanaged"
+)
+
+// States of an entry in a Status, comparing its upstream contents with the generated file.
+const (
+	// UpstreamUnchanged means the upstream contents match the generated file.
+	UpstreamUnchanged = "unchanged"
+	// UpstreamChanged means the upstream contents differ from the generated file.
+	UpstreamChanged = "changed"
+	// UpstreamNotGenerated means the upstream contents are available, but the entry was never generated to compare them with.
+	UpstreamNotGenerated = "not_generated"
+	// UpstreamUnavailable means the upstream contents could not be fetched.
+	UpstreamUnavailable = "unavailable"
+	// UpstreamRemoved means the entry no longer exists upstream, so it may have been renamed.
+	UpstreamRemoved = "removed"
+	// UpstreamUnchecked means upstream was not checked.
+	UpstreamUnchecked = "unchecked"
+)
+
+// OutputStatus is the state of an output of the spec.
+type OutputStatus struct {
+	Path  string `json:"path"`
+	State string `json:"state"`
+}
+
+// EntryStatus is the state of an entry. Ref is the ref its contents are fetched at, and Pinned reports whether that
+// ref is fixed, either by the entry itself or by a source ref that is a commit. Generated reports whether the
+// generated file has a section for the entry, and GeneratedRef is the commit recorded for it, if any.
+type EntryStatus struct {
+	Name         string `json:"name"`
+	Ref          string `json:"ref"`
+	Pinned       bool   `json:"pinned"`
+	Generated    bool   `json:"generated"`
+	GeneratedRef string `json:"generated_ref,omitempty"`
+	Upstream     string `json:"upstream"`
+}
+
+// SourceStatus is a source of a spec and the state of its entries.
+type SourceStatus struct {
+	Name     string        `json:"name"`
+	Provider string        `json:"provider"`
+	Repo     string        `json:"repo"`
+	Ref      string        `json:"ref"`
+	Entries  []EntryStatus `json:"entries"`
+}
+
+// Status is the result of comparing a spec with its generated files and upstream.
+type Status struct {
+	Config        string         `json:"config"`
+	SchemaVersion uint           `json:"schema_version"`
+	Outputs       []OutputStatus `json:"outputs"`
+	Sources       []SourceStatus `json:"sources"`
+}
+
+var outputStates = map[string]string{
+	OutputInSync:    "in sync with the spec",
+	OutputStale:     "out of date, the spec changed since it was generated",
+	OutputMissing:   "not generated yet",
+	OutputUnmanaged: "not generated by this version of ignoreit",
+}
+
+var upstreamStates = map[string]string{
+	UpstreamUnchanged:    "up to date",
+	UpstreamChanged:      "changed upstream",
+	UpstreamNotGenerated: "available upstream, run ignoreit generate to add it",
+	UpstreamUnavailable:  "unavailable upstream",
+	UpstreamRemoved:      "removed upstream, run ignoreit update to follow a rename",
+}
+
+// WriteText describes the outputs, followed by every entry under its source.
+func (result Status) WriteText(w io.Writer) error {
+	lines := []string{fmt.Sprintf("Spec %s (schema version %d)", result.Config, result.SchemaVersion)}
+	for _, output := range result.Outputs {
+		lines = append(lines, fmt.Sprintf("Output %s: %s", output.Path, outputStates[output.State]))
+	}
+	for _, source := range result.Sources {
+		lines = append(lines, "", source.Name)
+		for _, entry := range source.Entries {
+			var states []string
+			if entry.Pinned {
+				states = append(states, "pinned to "+entry.Ref)
+			}
+			if !entry.Generated {
+				states = append(states, "not generated")
+			} else if entry.GeneratedRef != "" && entry.GeneratedRef != entry.Ref {
+				states = append(states, "generated at "+shortRef(entry.GeneratedRef))
+			}
+			if state, ok := upstreamStates[entry.Upstream]; ok {
+				states = append(states, state)
+			}
+			if len(states) > 0 {
+				lines = append(lines, fmt.Sprintf("  %s: %s", entry.Name, strings.Join(states, ", ")))
+			} else {
+				lines = append(lines, "  "+entry.Name)
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortRef abbreviates commit hashes the way git does, leaving branch and tag names as they are.
+func shortRef(ref string) string {
+	if len(ref) == 40 {
+		return ref[:7]
+	}
+	return ref
+}
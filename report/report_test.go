@@ -0,0 +1,121 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/whoshuu/ignoreit/golden"
+)
+
+// checkGolden compares the JSON document of a result with testdata/<name>.json.
+func checkGolden(t *testing.T, name, command string, result interface{}, err error) {
+	var buffer bytes.Buffer
+	if werr := WriteJSON(&buffer, command, result, err); werr != nil {
+		t.Fatalf("Error should not be returned: %s", werr)
+	}
+	golden.Check(t, filepath.Join("testdata", name+".json"), buffer.Bytes())
+}
+
+func TestAddJSON(t *testing.T) {
+	checkGolden(t, "add", "add", Add{
+		Config:  ".ignoreit.yml",
+		Source:  "github/gitignore - master",
+		Added:   []string{"Go"},
+		Skipped: []Skipped{{"Python", "already in the spec"}, {"Nope", "not found in github/gitignore - master"}},
+	}, nil)
+}
+
+func TestRemoveJSON(t *testing.T) {
+	checkGolden(t, "remove", "remove", Remove{
+		Config:  ".ignoreit.yml",
+		Source:  "github/gitignore - master",
+		Removed: []string{"Go"},
+		Skipped: []Skipped{},
+	}, nil)
+}
+
+func TestGenerateJSON(t *testing.T) {
+	checkGolden(t, "generate", "generate", Generate{
+		Config:   ".ignoreit.yml",
+		Entries:  []Fetched{{"github/gitignore - master", "Go", true, 269}, {"github/gitignore - master", "Missing", false, 0}},
+		Outputs:  []Output{{".gitignore", "gitignore", 412}, {".dockerignore", "dockerignore", 398}},
+		Warnings: []string{"Go (github/gitignore - master): cannot translate \"!keep\" to dockerignore"},
+		Tracked:  []Tracked{{"app.exe", "*.exe", "Go (github/gitignore - master)"}},
+	}, nil)
+}
+
+func TestGenerateAllJSON(t *testing.T) {
+	failed := NewGenerate("web/.ignoreit.yml")
+	failed.Error = &Error{CodeConfig, "Error loading include base.yml"}
+	checkGolden(t, "generate-all", "generate", GenerateAll{
+		Configs: []Generate{
+			{Config: ".ignoreit.yml", Entries: []Fetched{}, Outputs: []Output{{".gitignore", "gitignore", 61}}, Warnings: []string{}, Tracked: []Tracked{}},
+			failed,
+		},
+		Generated: 1,
+	}, Coded(CodeGenerate, errors.New("1 of 2 config(s) failed to generate")))
+}
+
+func TestListJSON(t *testing.T) {
+	checkGolden(t, "list", "list", List{
+		Config: ".ignoreit.yml",
+		Sources: []Source{{
+			Name:     "github/gitignore - master",
+			Provider: "github",
+			Repo:     "github/gitignore",
+			Ref:      "master",
+			Entries:  []Entry{{Name: "Go"}, {Name: "Python", Ref: "6a1ac3d", Exclude: []string{"*.so"}, Append: []string{"/bin/"}}},
+		}},
+		Groups: []Group{{"Fixtures", []string{"/fixtures/"}}},
+		Custom: []string{".env"},
+	}, nil)
+}
+
+func TestExplainJSON(t *testing.T) {
+	checkGolden(t, "explain", "explain", Explain{
+		Config: ".ignoreit.yml",
+		Paths: []Explanation{
+			{Path: "app.exe", Ignored: true, Pattern: "*.exe", Section: "Go (github/gitignore - master)"},
+			{Path: "keep.exe", Pattern: "!keep.exe", Section: "Custom Patterns"},
+			{Path: "main.go"},
+		},
+	}, nil)
+}
+
+func TestLintJSON(t *testing.T) {
+	checkGolden(t, "lint", "lint", Lint{
+		Config: ".ignoreit.yml",
+		Root:   ".",
+		Paths:  4,
+		Sections: []LintSection{
+			{Section: "C (github/gitignore - master)", Hits: 1, Patterns: []LintPattern{{"*.o", 1}, {"*.so", 0}}, Dead: []string{"*.so"}},
+			{Section: "Custom Patterns", Hits: 1, Patterns: []LintPattern{{"build/", 1}}, Dead: []string{}},
+		},
+	}, nil)
+}
+
+func TestStatusJSON(t *testing.T) {
+	checkGolden(t, "status", "status", Status{
+		Config:        ".ignoreit.yml",
+		SchemaVersion: 2,
+		Outputs:       []OutputStatus{{".gitignore", OutputStale}, {".dockerignore", OutputMissing}},
+		Sources: []SourceStatus{{
+			Name:     "github/gitignore - master",
+			Provider: "github",
+			Repo:     "github/gitignore",
+			Ref:      "master",
+			Entries: []EntryStatus{
+				{Name: "Go", Ref: "master", Generated: true, GeneratedRef: "d0b3b5b6e8a5d1a8a2f5c8e2f6c1b1a0e3c4d5f6", Upstream: UpstreamChanged},
+				{Name: "Python", Ref: "6a1ac3d", Pinned: true, Generated: true, Upstream: UpstreamUnchanged},
+				{Name: "Rust", Ref: "master", Upstream: UpstreamNotGenerated},
+			},
+		}},
+	}, nil)
+}
+
+func TestErrorJSON(t *testing.T) {
+	checkGolden(t, "error", "add", nil, Coded(CodeConfig, errors.New("Error loading config .ignoreit.yml: yaml: line 1")))
+	checkGolden(t, "error-unknown", "add", nil, errors.New("boom"))
+}
@@ -7,30 +7,50 @@ import (
 	"sort"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/whoshuu/ignoreit/atomicfile"
+)
+
+const (
+	schemaVersion = 2
 )
 
 const (
-	schemaVersion = 1
+	// OrderSorted keeps sources and entries sorted by name whenever the config is saved. It is the default.
+	OrderSorted = "sorted"
+	// OrderPreserve keeps sources and entries in the order they are written, since later patterns in the generated
+	// file override earlier ones, such as negations re-including files ignored by a previous entry.
+	OrderPreserve = "preserve"
 )
 
 // Config encapsulates a specification of .gitignore entries and their sources.
 // It includes a list of custom strings that can be used as additional .gitignore patterns.
+// Groups hold further custom patterns organized under a name and description.
+// Include lists other spec files, by path or URL, whose sources and custom patterns are merged into this one.
+// Inherit merges the nearest spec of the same name in a parent directory of the repository, for nested specs in monorepos.
+// Outputs lists the ignore files to generate, defaulting to a single .gitignore when empty.
+// Order controls whether sources and entries are sorted when the config is saved, or kept in the order they are written.
 // The schema is versioned to enable forward and backward compatibility.
 type Config struct {
+	Include       []string `yaml:"include,omitempty"`
+	Inherit       bool     `yaml:"inherit,omitempty"`
 	Sources       Sources  `yaml:"sources"`
 	Custom        []string `yaml:"custom"`
+	Groups        []Group  `yaml:"groups,omitempty"`
+	Outputs       []Output `yaml:"outputs,omitempty"`
+	Order         string   `yaml:"order,omitempty"`
 	SchemaVersion uint     `yaml:"schema_version"`
 }
 
 // GetSource grabs a modifiable reference to a Source if it exists in the input config.
-// If a Source of the repo and branch name doesn't exist, nil is returned instead.
-func (config Config) GetSource(repo, branch string) *Source {
-	if repo == "" || branch == "" {
+// If a Source of the provider, repo and ref doesn't exist, nil is returned instead.
+func (config Config) GetSource(provider, repo, ref string) *Source {
+	if provider == "" || repo == "" || ref == "" {
 		return nil
 	}
 	var source *Source
 	for i := range config.Sources {
-		if config.Sources[i].Repo == repo && config.Sources[i].Branch == branch {
+		if config.Sources[i].Provider == provider && config.Sources[i].Repo == repo && config.Sources[i].Ref == ref {
 			source = &config.Sources[i]
 			break
 		}
@@ -40,22 +60,24 @@ func (config Config) GetSource(repo, branch string) *Source {
 
 // CreateSource creates a modifiable reference to a Source if it doesn't yet exist.
 // Otherwise, it returns that reference without modifying config.
-func (config *Config) CreateSource(repo, branch string) *Source {
-	if repo == "" || branch == "" {
+func (config *Config) CreateSource(provider, repo, ref string) *Source {
+	if provider == "" || repo == "" || ref == "" {
 		return nil
 	}
-	source := config.GetSource(repo, branch)
+	source := config.GetSource(provider, repo, ref)
 	if source == nil {
-		config.Sources = append(config.Sources, Source{repo, branch, []string{}})
+		config.Sources = append(config.Sources, Source{Provider: provider, Repo: repo, Ref: ref, Entries: Entries{}})
 		source = &config.Sources[len(config.Sources)-1]
 	}
 	return source
 }
 
-// Save will write the config to disk in YAML format for readability.
-// Prior to the write, the config is deduped and scrubbed.
-// Sources with no Entries will be removed from config.
-// Custom patterns are left unmodified as users are responsible for proper maintenance of that array.
+// Save will write the config to disk in YAML format for readability, or in TOML or JSON when the file name has their extension.
+// The file is replaced atomically, so an interrupted save never leaves a truncated config behind.
+// Prior to the write, the config is deduped and scrubbed, and sorted unless its order is preserved.
+// Sources with no Entries will be removed from config, unless they omit entries inherited from an include.
+// Custom patterns are left unmodified, in the order they are written, since AddCustom already keeps them unique.
+// Comments and blank lines of an existing YAML file are kept next to the lines they precede, along with the order of its keys.
 func (config *Config) Save(configFilename string) error {
 	config.clean()
 
@@ -63,42 +85,110 @@ func (config *Config) Save(configFilename string) error {
 	if err != nil {
 		return fmt.Errorf("error marshalling config [ %v ] to yaml: %s", config, err)
 	}
+	format := SpecFormat(configFilename)
+	if format != SpecYAML {
+		if data, err = encodeSpec(format, data); err != nil {
+			return fmt.Errorf("error marshalling config [ %v ] to %s: %s", config, format, err)
+		}
+	} else if previous, err := ioutil.ReadFile(configFilename); err == nil {
+		data = preserveLayout(previous, data)
+	}
 
-	return ioutil.WriteFile(configFilename, data, 0644)
+	return atomicfile.WriteFile(configFilename, data, 0644)
 }
 
 // LoadConfig will unmarshal a Config struct from a config file in the current working directory.
-// If the schema version of the loaded file is different from the tool's schema version, it is rejected.
+// The file is read as YAML, TOML or JSON depending on its extension, with the same semantics and validation.
+// Files written with an older schema version are migrated to the tool's schema version in memory.
+// Files written with a newer schema version than the tool supports are rejected.
 func LoadConfig(configFilename string) (Config, error) {
+	config, _, err := loadConfig(configFilename)
+	return config, err
+}
+
+// loadConfig implements LoadConfig, additionally returning the schema version the file was written with.
+func loadConfig(configFilename string) (Config, uint, error) {
 	config := Config{}
 	config.SchemaVersion = schemaVersion
 
 	if configFilename == "" {
-		return config, fmt.Errorf("cannot specify empty string for configFilename")
+		return config, 0, fmt.Errorf("cannot specify empty string for configFilename")
 	}
 
 	contents, err := ioutil.ReadFile(configFilename)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return config, nil
+			return config, schemaVersion, nil
 		}
 
-		return config, err
+		return config, 0, err
 	}
 
-	err = yaml.Unmarshal(contents, &config)
+	if contents, err = decodeSpec(SpecFormat(configFilename), contents); err != nil {
+		return config, 0, err
+	}
+	return parseConfig(contents)
+}
+
+// parseConfig unmarshals a Config from the contents of a config file, migrating it to the tool's schema version.
+// The schema version the contents were written with is returned along with the config.
+func parseConfig(contents []byte) (Config, uint, error) {
+	config := Config{}
+	config.SchemaVersion = schemaVersion
+
+	var document map[interface{}]interface{}
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return config, 0, err
+	}
+
+	version, err := documentVersion(document)
 	if err == nil {
-		err = config.checkSchema()
+		err = checkSchema(version)
+	}
+	if err == nil && version < schemaVersion {
+		if err = migrate(document, version); err == nil {
+			contents, err = yaml.Marshal(document)
+		}
+	}
+	if err != nil {
+		return config, version, err
 	}
 
-	return config, err
+	if err = yaml.Unmarshal(contents, &config); err == nil {
+		err = config.checkSources()
+	}
+	if err == nil {
+		err = checkOrder(config.Order)
+	}
+
+	return config, version, err
+}
+
+// Cleaned returns a copy of the config as Save writes it, with duplicate entries and empty sources removed, and sources
+// and entries sorted unless the config preserves their order. The config itself is left untouched.
+func (config Config) Cleaned() Config {
+	sources := make(Sources, len(config.Sources))
+	for i, source := range config.Sources {
+		source.Entries = append([]Entry{}, source.Entries...)
+		sources[i] = source
+	}
+	config.Sources = sources
+	config.clean()
+	return config
 }
 
 func (config *Config) clean() {
-	sort.Sort(config.Sources)
+	preserve := config.Order == OrderPreserve
+	if !preserve {
+		sort.Sort(config.Sources)
+	}
 	for i := 0; i < len(config.Sources); {
-		config.Sources[i].Clean()
-		if len(config.Sources[i].Entries) == 0 {
+		if preserve {
+			config.Sources[i].Dedupe()
+		} else {
+			config.Sources[i].Clean()
+		}
+		if len(config.Sources[i].Entries) == 0 && len(config.Sources[i].Omit) == 0 {
 			config.Sources = append(config.Sources[:i], config.Sources[i+1:]...)
 			continue
 		}
@@ -106,9 +196,39 @@ func (config *Config) clean() {
 	}
 }
 
-func (config Config) checkSchema() error {
-	if config.SchemaVersion != schemaVersion {
-		return fmt.Errorf("Schema version %d does not match expected version %d", config.SchemaVersion, schemaVersion)
+func checkSchema(version uint) error {
+	if version == 0 {
+		return fmt.Errorf("Schema version 0 is not a valid version")
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("Schema version %d is newer than version %d supported by this version of ignoreit, please upgrade ignoreit", version, schemaVersion)
+	}
+
+	return nil
+}
+
+func checkOrder(order string) error {
+	if order != "" && order != OrderSorted && order != OrderPreserve {
+		return fmt.Errorf("unknown order %q, expected %s or %s", order, OrderSorted, OrderPreserve)
+	}
+	return nil
+}
+
+// checkSources fills in the default provider of sources that don't name one and rejects unknown providers.
+// The exclude rules of every entry are compiled so that invalid regular expressions are reported on load.
+func (config *Config) checkSources() error {
+	for i := range config.Sources {
+		if config.Sources[i].Provider == "" {
+			config.Sources[i].Provider = DefaultProvider
+		}
+		if err := checkProvider(config.Sources[i].Provider); err != nil {
+			return fmt.Errorf("Source [%s]: %s", config.Sources[i], err)
+		}
+		for _, entry := range config.Sources[i].Entries {
+			if _, err := entry.Excluder(); err != nil {
+				return fmt.Errorf("Source [%s]: %s", config.Sources[i], err)
+			}
+		}
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -18,6 +19,7 @@ func randSeq(n int) string {
 }
 
 const (
+	providerName = "github"
 	repoName     = "github/gitignore"
 	branchName   = "master"
 	testFilename = ".ignoreit.test.yml"
@@ -37,26 +39,52 @@ custom:
 - .custompattern
 - .anothercustompattern
 schema_version: 1
+`
+	rawConfigV2 = `sources:
+- provider: gitlab
+  repo: group/templates
+  ref: main
+  entries:
+  - Go
+  - name: Python
+    ref: 0123456789abcdef0123456789abcdef01234567
+custom: []
+schema_version: 2
 `
 	rawConfigBadSchema = `sources: []
 custom: []
+schema_version: 3
+`
+	rawConfigBadProvider = `sources:
+- provider: sourceforge
+  repo: github/gitignore
+  ref: master
+  entries:
+  - Go
 schema_version: 2
 `
 )
 
 type sourceInput struct {
-	repo   string
-	branch string
+	provider string
+	repo     string
+	branch   string
 }
 
 var (
-	allInputs = []sourceInput{{"", ""}, {repoName, branchName}, {"", branchName}, {repoName, ""}}
+	allInputs = []sourceInput{
+		{"", "", ""},
+		{providerName, repoName, branchName},
+		{providerName, "", branchName},
+		{providerName, repoName, ""},
+		{"", repoName, branchName},
+	}
 )
 
 func TestCreateSourceSingle(t *testing.T) {
 	config := Config{}
 
-	source := config.CreateSource(repoName, branchName)
+	source := config.CreateSource(providerName, repoName, branchName)
 	if source == nil {
 		t.Errorf("Source should not be nil for repo: %s, branch: %s", repoName, branchName)
 	}
@@ -66,7 +94,7 @@ func TestCreateSourceSingleFromMany(t *testing.T) {
 	config := Config{}
 
 	for _, input := range allInputs {
-		config.CreateSource(input.repo, input.branch)
+		config.CreateSource(input.provider, input.repo, input.branch)
 	}
 
 	if len(config.Sources) != 1 {
@@ -77,8 +105,8 @@ func TestCreateSourceSingleFromMany(t *testing.T) {
 func TestCreateSourceAlreadyExists(t *testing.T) {
 	config := Config{}
 
-	config.CreateSource(repoName, branchName)
-	source := config.CreateSource(repoName, branchName)
+	config.CreateSource(providerName, repoName, branchName)
+	source := config.CreateSource(providerName, repoName, branchName)
 	if source == nil {
 		t.Errorf("Source should not be nil for repo: %s, branch: %s", repoName, branchName)
 	}
@@ -92,7 +120,7 @@ func TestCreateSourceMany(t *testing.T) {
 	config := Config{}
 
 	for i := 0; i < 1000; i++ {
-		config.CreateSource(randSeq(20), randSeq(20))
+		config.CreateSource(providerName, randSeq(20), randSeq(20))
 	}
 
 	if len(config.Sources) != 1000 {
@@ -104,7 +132,7 @@ func TestGetSourceEmpty(t *testing.T) {
 	config := Config{}
 
 	for _, input := range allInputs {
-		source := config.GetSource(input.repo, input.branch)
+		source := config.GetSource(input.provider, input.repo, input.branch)
 		if source != nil {
 			t.Errorf("Source should be nil for repo: %s, branch: %s", input.repo, input.branch)
 		}
@@ -114,8 +142,8 @@ func TestGetSourceEmpty(t *testing.T) {
 func TestGetSourceSingle(t *testing.T) {
 	config := Config{}
 
-	config.CreateSource(repoName, branchName)
-	source := config.GetSource(repoName, branchName)
+	config.CreateSource(providerName, repoName, branchName)
+	source := config.GetSource(providerName, repoName, branchName)
 	if source == nil {
 		t.Errorf("Source should not be nil for repo: %s, branch: %s", repoName, branchName)
 	}
@@ -144,8 +172,8 @@ func TestLoadConfigNonexistentFilename(t *testing.T) {
 		t.Errorf("Length of Custom patterns should be 0, got %d instead", len(config.Custom))
 	}
 
-	if config.SchemaVersion != 1 {
-		t.Errorf("SchemaVersion should be 1, got %d instead", config.SchemaVersion)
+	if config.SchemaVersion != 2 {
+		t.Errorf("SchemaVersion should be 2, got %d instead", config.SchemaVersion)
 	}
 }
 
@@ -189,16 +217,16 @@ func TestLoadConfigExistingFilename(t *testing.T) {
 
 	for _, expectedValue := range expectedValues {
 		actualSource := config.Sources[expectedValue.i]
-		if actualSource.Repo != expectedValue.repo || actualSource.Branch != expectedValue.branch {
-			t.Errorf("Source should be repo: %s, branch: %s, got repo: %s, branch: %s instead", expectedValue.repo, expectedValue.branch, actualSource.Repo, actualSource.Branch)
+		if actualSource.Provider != providerName || actualSource.Repo != expectedValue.repo || actualSource.Ref != expectedValue.branch {
+			t.Errorf("Source should be provider: %s, repo: %s, ref: %s, got provider: %s, repo: %s, ref: %s instead", providerName, expectedValue.repo, expectedValue.branch, actualSource.Provider, actualSource.Repo, actualSource.Ref)
 		}
 
 		if len(actualSource.Entries) != len(expectedValue.entries) {
 			t.Errorf("Length of Source Entries should be %d, got %d instead", len(expectedValue.entries), len(actualSource.Entries))
 		}
 		for i := range actualSource.Entries {
-			if actualSource.Entries[i] != expectedValue.entries[i] {
-				t.Errorf("Source should have %s at index %d, got %s instead", expectedValue.entries[i], i, actualSource.Entries[i])
+			if actualSource.Entries[i].Name != expectedValue.entries[i] {
+				t.Errorf("Source should have %s at index %d, got %s instead", expectedValue.entries[i], i, actualSource.Entries[i].Name)
 			}
 		}
 
@@ -214,8 +242,8 @@ func TestLoadConfigExistingFilename(t *testing.T) {
 		}
 	}
 
-	if config.SchemaVersion != 1 {
-		t.Errorf("SchemaVersion should be 1, got %d instead", config.SchemaVersion)
+	if config.SchemaVersion != 2 {
+		t.Errorf("SchemaVersion should be 2, got %d instead", config.SchemaVersion)
 	}
 }
 
@@ -227,8 +255,119 @@ func TestLoadConfigBadSchema(t *testing.T) {
 
 	_, err := LoadConfig(testFilename)
 
-	if err.Error() != "Schema version 2 does not match expected version 1" {
-		t.Errorf("Schema check should have failed for bad schema: %s", err)
+	if err == nil || err.Error() != "Schema version 3 is newer than version 2 supported by this version of ignoreit, please upgrade ignoreit" {
+		t.Errorf("Schema check should have failed for bad schema: %v", err)
+	}
+}
+
+func TestLoadConfigV2(t *testing.T) {
+	if err := ioutil.WriteFile(testFilename, []byte(rawConfigV2), 0644); err != nil {
+		panic(err)
+	}
+	defer os.Remove(testFilename)
+
+	config, err := LoadConfig(testFilename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	source := config.GetSource("gitlab", "group/templates", "main")
+	if source == nil {
+		t.Fatal("Source should not be nil for provider: gitlab, repo: group/templates, ref: main")
+	}
+
+	expectedEntries := []Entry{{Name: "Go"}, {Name: "Python", Ref: "0123456789abcdef0123456789abcdef01234567"}}
+	if len(source.Entries) != len(expectedEntries) {
+		t.Fatalf("Length of Source Entries should be %d, got %d instead", len(expectedEntries), len(source.Entries))
+	}
+	for i := range expectedEntries {
+		if source.Entries[i].Name != expectedEntries[i].Name || source.Entries[i].Ref != expectedEntries[i].Ref {
+			t.Errorf("Source should have %v at index %d, got %v instead", expectedEntries[i], i, source.Entries[i])
+		}
+	}
+
+	expectedLink := "https://gitlab.com/group/templates/-/raw/0123456789abcdef0123456789abcdef01234567/Python.gitignore"
+	if link := source.GetDownloadLink(source.Entries[1]); link != expectedLink {
+		t.Errorf("Download link should be %s, got %s instead", expectedLink, link)
+	}
+}
+
+func TestLoadConfigBadProvider(t *testing.T) {
+	if err := ioutil.WriteFile(testFilename, []byte(rawConfigBadProvider), 0644); err != nil {
+		panic(err)
+	}
+	defer os.Remove(testFilename)
+
+	if _, err := LoadConfig(testFilename); err == nil {
+		t.Error("Error should be returned for unknown provider")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	if err := ioutil.WriteFile(testFilename, []byte(rawConfig), 0644); err != nil {
+		panic(err)
+	}
+	defer os.Remove(testFilename)
+
+	version, err := Migrate(testFilename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	if version != 1 {
+		t.Errorf("Migrated version should be 1, got %d instead", version)
+	}
+
+	version, err = Migrate(testFilename)
+	if err != nil || version != 2 {
+		t.Errorf("Migrating again should report version 2, got %d and %v instead", version, err)
+	}
+
+	contents, _ := ioutil.ReadFile(testFilename)
+	expected := `sources:
+- provider: github
+  repo: github/gitignore
+  ref: ghfw
+  entries:
+  - Ada
+  - Python
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  - C++
+  - CMake
+  - Go
+custom:
+- .custompattern
+- .anothercustompattern
+schema_version: 2
+`
+	if string(contents) != expected {
+		t.Errorf("Migrated config should be:\n%s\ngot:\n%s", expected, contents)
+	}
+}
+
+func TestMigrateMissingVersion(t *testing.T) {
+	config, version, err := parseConfig([]byte("sources:\n- repo: github/gitignore\n  branch: master\n  entries:\n  - Go\n"))
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+	if version != 1 {
+		t.Errorf("Version of a spec without schema_version should be 1, got %d instead", version)
+	}
+	if len(config.Sources) != 1 || config.Sources[0].Ref != "master" || config.Sources[0].Provider != DefaultProvider {
+		t.Errorf("Source should be migrated to github/gitignore at master, got %+v instead", config.Sources)
+	}
+
+	if _, version, err = parseConfig([]byte("")); err != nil || version != schemaVersion {
+		t.Errorf("Empty spec should be at version %d, got %d and %v instead", schemaVersion, version, err)
+	}
+}
+
+func TestMigrateMissingBranch(t *testing.T) {
+	_, _, err := parseConfig([]byte("sources:\n- repo: github/gitignore\n  entries:\n  - Go\nschema_version: 1\n"))
+	if err == nil || !strings.Contains(err.Error(), "has no branch") {
+		t.Errorf("Error should be returned for a source without a branch, got %v instead", err)
 	}
 }
 
@@ -0,0 +1,43 @@
+package spec
+
+import (
+	"strings"
+)
+
+// AddCustom appends the pattern to config.Custom unless it is already there.
+// A non-empty comment is written as comment lines directly above the pattern, so it documents the pattern in generated files.
+// It reports whether the pattern was added.
+func (config *Config) AddCustom(pattern, comment string) bool {
+	for _, custom := range config.Custom {
+		if custom == pattern {
+			return false
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			config.Custom = append(config.Custom, "# "+line)
+		}
+	}
+	config.Custom = append(config.Custom, pattern)
+	return true
+}
+
+// RemoveCustom removes the pattern from config.Custom, along with the comment lines directly above it,
+// unless they also document the pattern that follows it. It reports whether the pattern was found.
+func (config *Config) RemoveCustom(pattern string) bool {
+	for i, custom := range config.Custom {
+		if custom != pattern {
+			continue
+		}
+		start := i
+		if i+1 == len(config.Custom) || strings.HasPrefix(config.Custom[i+1], "#") {
+			for start > 0 && strings.HasPrefix(config.Custom[start-1], "#") {
+				start--
+			}
+		}
+		config.Custom = append(config.Custom[:start], config.Custom[i+1:]...)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddCustom(t *testing.T) {
+	config := Config{Custom: []string{".env"}}
+	if config.AddCustom(".env", "") {
+		t.Errorf("Adding an existing pattern should be skipped, got added instead")
+	}
+	if !config.AddCustom("/tmp-fixtures/", "Scratch data of the integration suite\nRecreated on every run") {
+		t.Errorf("Adding a new pattern should succeed, got skipped instead")
+	}
+
+	expected := []string{".env", "# Scratch data of the integration suite", "# Recreated on every run", "/tmp-fixtures/"}
+	if !reflect.DeepEqual(config.Custom, expected) {
+		t.Errorf("Custom patterns should be %v, got %v instead", expected, config.Custom)
+	}
+}
+
+func TestRemoveCustom(t *testing.T) {
+	config := Config{Custom: []string{"# Secrets", ".env", "# Fixtures", "# Recreated on every run", "/tmp-fixtures/", "# Logs", "*.log"}}
+	if config.RemoveCustom("/dist/") {
+		t.Errorf("Removing a missing pattern should fail, got removed instead")
+	}
+	if !config.RemoveCustom("/tmp-fixtures/") {
+		t.Errorf("Removing an existing pattern should succeed, got not found instead")
+	}
+
+	expected := []string{"# Secrets", ".env", "# Logs", "*.log"}
+	if !reflect.DeepEqual(config.Custom, expected) {
+		t.Errorf("Custom patterns should be %v, got %v instead", expected, config.Custom)
+	}
+
+	config = Config{Custom: []string{"# Build outputs", "/bin/", "/dist/"}}
+	config.RemoveCustom("/bin/")
+	expected = []string{"# Build outputs", "/dist/"}
+	if !reflect.DeepEqual(config.Custom, expected) {
+		t.Errorf("Custom patterns should keep the comment of the next pattern as %v, got %v instead", expected, config.Custom)
+	}
+}
@@ -0,0 +1,102 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/whoshuu/ignoreit/walk"
+)
+
+// FindConfigs walks the tree rooted at root and returns the path of every config file named configName, in any format.
+// Directories with configs in several formats only return the one that takes precedence, as ConfigIn does.
+// Directories skipped by walk.Skip are never walked, and only directories containing a config are checked for the others.
+// Paths are returned in sorted order, so parents come before their children.
+func FindConfigs(root, configName string) ([]string, error) {
+	names := make(map[string]bool)
+	for _, name := range specNames(configName) {
+		names[name] = true
+	}
+
+	var filenames []string
+	checked := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != root && walk.Skip(info.Name()) {
+			return filepath.SkipDir
+		}
+		if dir := filepath.Dir(path); !info.IsDir() && names[info.Name()] && !checked[dir] {
+			checked[dir] = true
+			if filename, found := ConfigIn(dir, configName); found {
+				filenames = append(filenames, filename)
+			}
+		}
+		return nil
+	})
+
+	sort.Strings(filenames)
+	return filenames, err
+}
+
+// ConfigIn returns the config file named configName in dir, trying each spec format in order of precedence
+// by replacing the extension of configName. If none exists, the path of configName in dir is returned instead.
+// The second return value reports whether the returned config exists.
+func ConfigIn(dir, configName string) (string, bool) {
+	for _, name := range specNames(configName) {
+		if candidate := filepath.Join(dir, name); isFile(candidate) {
+			return candidate, true
+		}
+	}
+	return filepath.Join(dir, configName), false
+}
+
+// FindConfig searches dir and its ancestors for a config file named configName in any format, stopping at the root of the git
+// repository containing dir. If no config exists, the path where one should be created is returned instead,
+// which is the root of the repository, or dir itself outside of a repository. The second return value reports
+// whether the returned config exists.
+func FindConfig(dir, configName string) (string, bool) {
+	for current := dir; ; {
+		candidate, found := ConfigIn(current, configName)
+		if found {
+			return candidate, true
+		}
+		if isRepoRoot(current) {
+			return candidate, false
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return filepath.Join(dir, configName), false
+		}
+		current = parent
+	}
+}
+
+// findParentConfig returns the nearest config file with the same name as configFilename in an ancestor directory.
+// The search stops at the root of the git repository containing configFilename.
+// If there is no parent config, an empty string is returned.
+func findParentConfig(configFilename string) string {
+	dir := filepath.Dir(configFilename)
+	if isRepoRoot(dir) || filepath.Dir(dir) == dir {
+		return ""
+	}
+
+	filename, found := FindConfig(filepath.Dir(dir), filepath.Base(configFilename))
+	if !found {
+		return ""
+	}
+	return filename
+}
+
+// isRepoRoot reports whether dir is the top-level directory of a git repository, identified by its .git entry.
+func isRepoRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,104 @@
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// excludeRegexpPrefix marks an exclude rule as a regular expression rather than an exact line.
+const excludeRegexpPrefix = "re:"
+
+// Entry is a single .gitignore file of a Source, named by its path in the repository without the .gitignore suffix.
+// Ref optionally overrides the ref of the Source for this entry alone, which can be used to pin it to a commit.
+// Exclude lists lines to strip from the fetched contents, either exact lines or regular expressions prefixed with re:.
+// Append lists extra lines to add after the fetched contents.
+// Entries without options are written as a plain name to keep specs readable.
+type Entry struct {
+	Name    string   `yaml:"name"`
+	Ref     string   `yaml:"ref,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	Append  []string `yaml:"append,omitempty"`
+}
+
+// entryOptions has the same fields as Entry without its YAML methods, to avoid recursing while (un)marshalling.
+type entryOptions Entry
+
+// UnmarshalYAML accepts either a plain entry name or a mapping with the name and its options.
+func (entry *Entry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&entry.Name); err == nil {
+		return nil
+	}
+
+	var options entryOptions
+	if err := unmarshal(&options); err != nil {
+		return err
+	}
+	*entry = Entry(options)
+	return nil
+}
+
+// MarshalYAML writes the entry as a plain name unless it has options set.
+func (entry Entry) MarshalYAML() (interface{}, error) {
+	if entry.Ref == "" && len(entry.Exclude) == 0 && len(entry.Append) == 0 {
+		return entry.Name, nil
+	}
+	return entryOptions(entry), nil
+}
+
+// Excluder compiles the exclude rules of the entry into a function reporting whether a line should be stripped.
+// Exact rules match lines with surrounding whitespace removed, while regular expressions match anywhere in the line.
+func (entry Entry) Excluder() (func(line string) bool, error) {
+	exact := make(map[string]bool)
+	var expressions []*regexp.Regexp
+	for _, rule := range entry.Exclude {
+		if !strings.HasPrefix(rule, excludeRegexpPrefix) {
+			if strings.TrimSpace(rule) != "" {
+				exact[strings.TrimSpace(rule)] = true
+			}
+			continue
+		}
+
+		expression, err := regexp.Compile(strings.TrimPrefix(rule, excludeRegexpPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude rule %q for entry %s: %s", rule, entry.Name, err)
+		}
+		expressions = append(expressions, expression)
+	}
+
+	return func(line string) bool {
+		if exact[strings.TrimSpace(line)] {
+			return true
+		}
+		for _, expression := range expressions {
+			if expression.MatchString(line) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Entries is a collection of Entry structs, sortable by name.
+type Entries []Entry
+
+func (entries Entries) Len() int {
+	return len(entries)
+}
+
+func (entries Entries) Less(i, j int) bool {
+	return entries[i].Name < entries[j].Name
+}
+
+func (entries Entries) Swap(i, j int) {
+	entries[i], entries[j] = entries[j], entries[i]
+}
+
+// Names returns the name of every entry, in order.
+func (entries Entries) Names() []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
@@ -0,0 +1,200 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Formats of spec files, which are detected by the extension of their name.
+// Every format describes the same document, and is converted to YAML before it is parsed and validated.
+const (
+	SpecYAML = "yaml"
+	SpecTOML = "toml"
+	SpecJSON = "json"
+)
+
+// specExtensions maps every spec format to the extension of its files, in order of precedence.
+var specExtensions = []struct {
+	format    string
+	extension string
+}{
+	{SpecYAML, ".yml"},
+	{SpecTOML, ".toml"},
+	{SpecJSON, ".json"},
+}
+
+// SpecFormat returns the format of the spec file named filename, which may also be a URL.
+// Files with an unknown extension are read as YAML.
+func SpecFormat(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".toml":
+		return SpecTOML
+	case ".json":
+		return SpecJSON
+	}
+	return SpecYAML
+}
+
+// SpecFilename returns the name of the spec file filename would have in format, by replacing its extension.
+func SpecFilename(filename, format string) (string, error) {
+	for _, spec := range specExtensions {
+		if spec.format == format {
+			return strings.TrimSuffix(filename, filepath.Ext(filename)) + spec.extension, nil
+		}
+	}
+	return "", fmt.Errorf("unknown spec format %q, expected %s, %s or %s", format, SpecYAML, SpecTOML, SpecJSON)
+}
+
+// specNames returns the names a spec file named configName can have in every format, in order of precedence.
+func specNames(configName string) []string {
+	var names []string
+	for _, spec := range specExtensions {
+		names = append(names, strings.TrimSuffix(configName, filepath.Ext(configName))+spec.extension)
+	}
+	return names
+}
+
+// decodeSpec converts the contents of a spec file in format to YAML, so every format is parsed the same way.
+func decodeSpec(format string, contents []byte) ([]byte, error) {
+	if format == SpecYAML || len(bytes.TrimSpace(contents)) == 0 {
+		return contents, nil
+	}
+
+	var document interface{}
+	switch format {
+	case SpecJSON:
+		if err := json.Unmarshal(contents, &document); err != nil {
+			return nil, err
+		}
+	case SpecTOML:
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(contents), &table); err != nil {
+			return nil, err
+		}
+		document = table
+	}
+	return yaml.Marshal(document)
+}
+
+// encodeSpec converts a spec marshalled to YAML into format. JSON keeps the order of its keys,
+// while TOML tables are written with their plain keys first, in alphabetical order.
+func encodeSpec(format string, data []byte) ([]byte, error) {
+	if format == SpecYAML {
+		return data, nil
+	}
+
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if format == SpecTOML {
+		var buf bytes.Buffer
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(toTOML(document)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, document, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// toTOML converts a YAML document into the tables and arrays of a TOML document, leaving out null values.
+// TOML arrays cannot mix types, so in arrays that mix plain names and tables, such as entries with options,
+// every name is written as a table with that name.
+func toTOML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		table := make(map[string]interface{})
+		for _, item := range v {
+			if item.Value != nil {
+				table[fmt.Sprint(item.Key)] = toTOML(item.Value)
+			}
+		}
+		return table
+	case []interface{}:
+		tables := 0
+		for _, item := range v {
+			if _, ok := item.(yaml.MapSlice); ok {
+				tables++
+			}
+		}
+		if tables == 0 {
+			return v
+		}
+
+		array := []map[string]interface{}{}
+		for _, item := range v {
+			if _, ok := item.(yaml.MapSlice); !ok {
+				item = yaml.MapSlice{{Key: "name", Value: item}}
+			}
+			array = append(array, toTOML(item).(map[string]interface{}))
+		}
+		return array
+	}
+	return value
+}
+
+// writeJSON writes the document as indented JSON, keeping the order of the keys of its mappings.
+func writeJSON(buf *bytes.Buffer, value interface{}, indent string) error {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, item := range v {
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return err
+			}
+			buf.WriteString(indent + "  " + string(key) + ": ")
+			if err = writeJSON(buf, item.Value, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range v {
+			buf.WriteString(indent + "  ")
+			if err := writeJSON(buf, item, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "]")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
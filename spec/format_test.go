@@ -0,0 +1,153 @@
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	rawFormatYAML = `sources:
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  - Go
+  - name: Node
+    exclude:
+    - node_modules/
+custom:
+- .env
+groups:
+- name: Fixtures
+  description: Scratch data.
+  patterns:
+  - /tmp-fixtures/
+order: preserve
+schema_version: 2
+`
+	rawFormatTOML = `custom = [".env"]
+order = "preserve"
+schema_version = 2
+
+[[sources]]
+provider = "github"
+repo = "github/gitignore"
+ref = "master"
+entries = [{ name = "Go" }, { name = "Node", exclude = ["node_modules/"] }]
+
+[[groups]]
+name = "Fixtures"
+description = "Scratch data."
+patterns = ["/tmp-fixtures/"]
+`
+	rawFormatJSON = `{
+  "sources": [
+    {
+      "provider": "github",
+      "repo": "github/gitignore",
+      "ref": "master",
+      "entries": ["Go", {"name": "Node", "exclude": ["node_modules/"]}]
+    }
+  ],
+  "custom": [".env"],
+  "groups": [{"name": "Fixtures", "description": "Scratch data.", "patterns": ["/tmp-fixtures/"]}],
+  "order": "preserve",
+  "schema_version": 2
+}
+`
+)
+
+func TestSpecFormats(t *testing.T) {
+	dir := writeSpecs(map[string]string{"spec.yml": rawFormatYAML, "spec.toml": rawFormatTOML, "spec.json": rawFormatJSON})
+	defer os.RemoveAll(dir)
+
+	expected, err := LoadConfig(filepath.Join(dir, "spec.yml"))
+	if err != nil {
+		t.Fatalf("Loading the YAML spec should succeed, got %v instead", err)
+	}
+	for _, name := range []string{"spec.toml", "spec.json"} {
+		filename := filepath.Join(dir, name)
+		config, err := LoadConfig(filename)
+		if err != nil {
+			t.Errorf("Loading %s should succeed, got %v instead", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("Config loaded from %s should be %+v, got %+v instead", name, expected, config)
+		}
+
+		if err = config.Save(filename); err != nil {
+			t.Errorf("Saving %s should succeed, got %v instead", name, err)
+			continue
+		}
+		if saved, err := LoadConfig(filename); err != nil || !reflect.DeepEqual(saved, expected) {
+			t.Errorf("Config saved to %s should load as %+v, got %+v and %v instead", name, expected, saved, err)
+		}
+	}
+}
+
+func TestSpecFormatValidation(t *testing.T) {
+	dir := writeSpecs(map[string]string{
+		"order.toml":   "order = \"random\"\nschema_version = 2\n",
+		"newer.json":   `{"schema_version": 99}`,
+		"invalid.toml": "sources = [\n",
+	})
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"order.toml", "newer.json", "invalid.toml"} {
+		if _, err := LoadConfig(filepath.Join(dir, name)); err == nil {
+			t.Errorf("Loading %s should fail, got no error instead", name)
+		}
+	}
+}
+
+func TestConfigIn(t *testing.T) {
+	dir := writeSpecs(map[string]string{".ignoreit.toml": rawFormatTOML, ".ignoreit.json": rawFormatJSON})
+	defer os.RemoveAll(dir)
+
+	filename, found := ConfigIn(dir, ".ignoreit.yml")
+	if !found || filepath.Base(filename) != ".ignoreit.toml" {
+		t.Errorf("Config found in the directory should be .ignoreit.toml, got %s (found %v) instead", filename, found)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, ".ignoreit.yml"), []byte(rawFormatYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filenames, err := FindConfigs(dir, ".ignoreit.yml")
+	if err != nil || len(filenames) != 1 || filepath.Base(filenames[0]) != ".ignoreit.yml" {
+		t.Errorf("Configs found in the tree should be [.ignoreit.yml], got %v and %v instead", filenames, err)
+	}
+}
+
+func TestFindConfigs(t *testing.T) {
+	dir := writeSpecs(map[string]string{
+		".ignoreit.yml":                      rawFormatYAML,
+		"api/.ignoreit.json":                 rawFormatJSON,
+		"api/.ignoreit.toml":                 rawFormatTOML,
+		"api/docs/README.md":                 "# API\n",
+		"web/.ignoreit.yml.bak":              rawFormatYAML,
+		"web/src/components/index.js":        "",
+		"web/node_modules/lib/.ignoreit.yml": rawFormatYAML,
+		"vendor/lib/.ignoreit.yml":           rawFormatYAML,
+		".hg/store/.ignoreit.yml":            rawFormatYAML,
+	})
+	defer os.RemoveAll(dir)
+
+	filenames, err := FindConfigs(dir, ".ignoreit.yml")
+	expected := []string{filepath.Join(dir, ".ignoreit.yml"), filepath.Join(dir, "api", ".ignoreit.toml")}
+	if err != nil || !reflect.DeepEqual(filenames, expected) {
+		t.Errorf("Configs found in the tree should be %v, got %v and %v instead", expected, filenames, err)
+	}
+}
+
+func TestSpecFilename(t *testing.T) {
+	if filename, _ := SpecFilename("dir/.ignoreit.yml", SpecTOML); filename != "dir/.ignoreit.toml" {
+		t.Errorf("TOML spec filename should be dir/.ignoreit.toml, got %s instead", filename)
+	}
+	if _, err := SpecFilename(".ignoreit.yml", "ini"); err == nil {
+		t.Errorf("Unknown spec format should be rejected, got no error instead")
+	}
+}
@@ -0,0 +1,50 @@
+package spec
+
+import (
+	"fmt"
+)
+
+// Group is a named list of custom patterns, documented by a description that is rendered as a comment.
+// Groups are generated after every source unless Before or After names a source to place them next to.
+// A source is referenced by its repo, optionally followed by @ref and optionally prefixed by provider:
+// Ex: github/gitignore, github/gitignore@master and github:github/gitignore@master are valid references.
+type Group struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Patterns    []string `yaml:"patterns"`
+	Before      string   `yaml:"before,omitempty"`
+	After       string   `yaml:"after,omitempty"`
+}
+
+// Validate checks that the group is named uniquely, placed at most once, and only references sources of the config.
+func (group Group) Validate(config Config) error {
+	if group.Name == "" {
+		return fmt.Errorf("custom group must have a name")
+	}
+	defined := 0
+	for _, other := range config.Groups {
+		if other.Name == group.Name {
+			defined++
+		}
+	}
+	if defined > 1 {
+		return fmt.Errorf("custom group %s is defined %d times, but group names must be unique", group.Name, defined)
+	}
+	if group.Before != "" && group.After != "" {
+		return fmt.Errorf("custom group %s cannot be placed both before %s and after %s", group.Name, group.Before, group.After)
+	}
+
+	for _, reference := range []string{group.Before, group.After} {
+		if reference == "" {
+			continue
+		}
+		found := false
+		for _, source := range config.Sources {
+			found = found || source.Matches(reference)
+		}
+		if !found {
+			return fmt.Errorf("custom group %s references unknown source %s", group.Name, reference)
+		}
+	}
+	return nil
+}
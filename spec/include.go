@@ -0,0 +1,158 @@
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/whoshuu/ignoreit/network"
+)
+
+// Expand merges every spec included by the config into a single config used for generation.
+// Includes are merged in order, so later includes take precedence over earlier ones, and the config itself takes
+// precedence over all of them. Included specs may include further specs, but cycles are rejected.
+// If the config inherits from its parent, the nearest config of the same name in an ancestor directory of the
+// repository is merged first, with the lowest precedence.
+// Relative include paths are resolved against the location of the including spec, which is configFilename here.
+// Outputs and the order setting only apply to the config itself, and are not merged from its includes.
+// The config itself is not modified, so it can still be saved without inlining its includes.
+func (config Config) Expand(configFilename string) (Config, error) {
+	location, err := filepath.Abs(configFilename)
+	if err != nil {
+		return config, err
+	}
+	return expand(config, []string{location})
+}
+
+// expand merges the includes of the config loaded from the last location of the stack, which lists every spec
+// currently being expanded so that cycles can be detected.
+func expand(config Config, stack []string) (Config, error) {
+	merged := Config{SchemaVersion: config.SchemaVersion}
+	from := stack[len(stack)-1]
+
+	var locations []string
+	if config.Inherit && !isURL(from) {
+		if parent := findParentConfig(from); parent != "" {
+			locations = append(locations, parent)
+		}
+	}
+	for _, include := range config.Include {
+		location, err := resolveInclude(from, include)
+		if err != nil {
+			return merged, err
+		}
+		locations = append(locations, location)
+	}
+
+	for _, location := range locations {
+		for _, visited := range stack {
+			if visited == location {
+				return merged, fmt.Errorf("include cycle detected: %s -> %s", strings.Join(stack, " -> "), location)
+			}
+		}
+
+		included, err := loadInclude(location)
+		if err != nil {
+			return merged, fmt.Errorf("error including %s: %s", location, err)
+		}
+		if included, err = expand(included, append(stack, location)); err != nil {
+			return merged, err
+		}
+		merged.merge(included)
+	}
+
+	merged.merge(config)
+	merged.Outputs = config.Outputs
+	merged.Order = config.Order
+	return merged, nil
+}
+
+// merge adds the sources, custom patterns and groups of other to config, with other taking precedence.
+// Entries and groups of the same name are replaced, custom patterns are deduped, and omitted entries are removed.
+func (config *Config) merge(other Config) {
+	for _, otherSource := range other.Sources {
+		source := config.CreateSource(otherSource.Provider, otherSource.Repo, otherSource.Ref)
+		for _, entry := range otherSource.Entries {
+			if existing := source.GetEntry(entry.Name); existing != nil {
+				*existing = entry
+			} else {
+				source.Entries = append(source.Entries, entry)
+			}
+		}
+		for _, omitted := range otherSource.Omit {
+			source.RemoveEntry(omitted)
+		}
+	}
+
+	for _, pattern := range other.Custom {
+		found := false
+		for _, existing := range config.Custom {
+			found = found || existing == pattern
+		}
+		if !found {
+			config.Custom = append(config.Custom, pattern)
+		}
+	}
+
+	for _, group := range other.Groups {
+		replaced := false
+		for i := range config.Groups {
+			if config.Groups[i].Name == group.Name {
+				config.Groups[i] = group
+				replaced = true
+			}
+		}
+		if !replaced {
+			config.Groups = append(config.Groups, group)
+		}
+	}
+}
+
+// resolveInclude turns an include into an absolute path or URL, relative to the location of the including spec.
+func resolveInclude(from, include string) (string, error) {
+	if isURL(include) {
+		return include, nil
+	}
+	if isURL(from) {
+		base, err := url.Parse(from)
+		if err != nil {
+			return "", err
+		}
+		reference, err := url.Parse(include)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(reference).String(), nil
+	}
+	if filepath.IsAbs(include) {
+		return filepath.Clean(include), nil
+	}
+	return filepath.Join(filepath.Dir(from), include), nil
+}
+
+// loadInclude reads an included spec from a local path or downloads it from a URL, in the format of its extension.
+// Unlike LoadConfig, a missing included spec is an error.
+func loadInclude(location string) (Config, error) {
+	var contents []byte
+	var err error
+	if isURL(location) {
+		contents, err = network.Fetch(location)
+	} else {
+		contents, err = ioutil.ReadFile(location)
+	}
+	if err == nil {
+		contents, err = decodeSpec(SpecFormat(location), contents)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	config, _, err := parseConfig(contents)
+	return config, err
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
@@ -0,0 +1,193 @@
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	rawBaseline = `sources:
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  - Global/JetBrains
+  - Global/macOS
+  - name: Go
+    exclude:
+    - vendor/
+custom:
+- .env
+schema_version: 2
+`
+	rawChild = `include:
+- baseline.yml
+sources:
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  - Go
+  omit:
+  - Global/JetBrains
+custom:
+- .env
+- /dist/
+outputs:
+- path: .gitignore
+schema_version: 2
+`
+)
+
+func writeSpecs(specs map[string]string) string {
+	dir, err := ioutil.TempDir("", "ignoreit-include")
+	if err != nil {
+		panic(err)
+	}
+	for name, contents := range specs {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return dir
+}
+
+func TestExpand(t *testing.T) {
+	dir := writeSpecs(map[string]string{"baseline.yml": rawBaseline, ".ignoreit.yml": rawChild})
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ".ignoreit.yml")
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	expanded, err := config.Expand(filename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	if len(expanded.Sources) != 1 {
+		t.Fatalf("Length of Sources should be 1, got %d instead", len(expanded.Sources))
+	}
+
+	entries := expanded.Sources[0].Entries
+	expectedNames := []string{"Global/macOS", "Go"}
+	if len(entries) != len(expectedNames) {
+		t.Fatalf("Entries should be %v, got %v instead", expectedNames, entries.Names())
+	}
+	for i := range expectedNames {
+		if entries[i].Name != expectedNames[i] {
+			t.Errorf("Source should have %s at index %d, got %s instead", expectedNames[i], i, entries[i].Name)
+		}
+	}
+	if len(entries[1].Exclude) != 0 {
+		t.Errorf("Child entry should replace the inherited entry and its options, got %v instead", entries[1])
+	}
+
+	expectedCustom := []string{".env", "/dist/"}
+	if len(expanded.Custom) != len(expectedCustom) || expanded.Custom[0] != expectedCustom[0] || expanded.Custom[1] != expectedCustom[1] {
+		t.Errorf("Custom patterns should be %v, got %v instead", expectedCustom, expanded.Custom)
+	}
+
+	if len(expanded.Outputs) != 1 || len(expanded.Include) != 0 {
+		t.Errorf("Expanded config should keep outputs and drop includes, got %v and %v instead", expanded.Outputs, expanded.Include)
+	}
+
+	if len(config.Sources[0].Entries) != 1 {
+		t.Errorf("Expanding should not modify the config, got entries %v", config.Sources[0].Entries.Names())
+	}
+}
+
+func TestExpandCycle(t *testing.T) {
+	dir := writeSpecs(map[string]string{
+		"a.yml": "include:\n- b.yml\nschema_version: 2\n",
+		"b.yml": "include:\n- a.yml\nschema_version: 2\n",
+	})
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "a.yml")
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	if _, err := config.Expand(filename); err == nil {
+		t.Error("Error should be returned for an include cycle")
+	}
+}
+
+func TestExpandMissingInclude(t *testing.T) {
+	dir := writeSpecs(map[string]string{".ignoreit.yml": "include:\n- missing.yml\nschema_version: 2\n"})
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ".ignoreit.yml")
+	config, _ := LoadConfig(filename)
+	if _, err := config.Expand(filename); err == nil {
+		t.Error("Error should be returned for a missing include")
+	}
+}
+
+func TestExpandInherit(t *testing.T) {
+	dir := writeSpecs(map[string]string{".ignoreit.yml": rawBaseline})
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		panic(err)
+	}
+	service := filepath.Join(dir, "services", "api")
+	if err := os.MkdirAll(service, 0755); err != nil {
+		panic(err)
+	}
+	filename := filepath.Join(service, ".ignoreit.yml")
+	if err := ioutil.WriteFile(filename, []byte("inherit: true\ncustom:\n- /api-build/\nschema_version: 2\n"), 0644); err != nil {
+		panic(err)
+	}
+
+	filenames, err := FindConfigs(dir, ".ignoreit.yml")
+	if err != nil || len(filenames) != 2 || filenames[1] != filename {
+		t.Errorf("Should find both configs with the parent first, got %v and %v instead", filenames, err)
+	}
+
+	config, _ := LoadConfig(filename)
+	expanded, err := config.Expand(filename)
+	if err != nil {
+		t.Fatalf("Error should not be returned: %s", err)
+	}
+
+	if len(expanded.Sources) != 1 || len(expanded.Sources[0].Entries) != 3 {
+		t.Errorf("Sources should be inherited from the parent config, got %v instead", expanded.Sources)
+	}
+	if len(expanded.Custom) != 2 || expanded.Custom[1] != "/api-build/" {
+		t.Errorf("Custom patterns should be merged after the parent's, got %v instead", expanded.Custom)
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := writeSpecs(map[string]string{".ignoreit.yml": rawBaseline})
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		panic(err)
+	}
+	nested := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		panic(err)
+	}
+
+	filename, found := FindConfig(nested, ".ignoreit.yml")
+	if !found || filename != filepath.Join(dir, ".ignoreit.yml") {
+		t.Errorf("Config at the repository root should be found, got %s (found: %t) instead", filename, found)
+	}
+
+	filename, found = FindConfig(nested, ".ignoreit.other.yml")
+	if found || filename != filepath.Join(dir, ".ignoreit.other.yml") {
+		t.Errorf("Missing config should be placed at the repository root, got %s (found: %t) instead", filename, found)
+	}
+}
@@ -0,0 +1,348 @@
+package spec
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyAliases are the previous names of keys that a migration renamed, so that their comments follow the new key.
+var keyAliases = map[string]string{"branch": "ref"}
+
+// blockIndicator matches the value of a key introducing a literal or folded block scalar.
+var blockIndicator = regexp.MustCompile(`^[|>][-+0-9]*$`)
+
+// layoutLine is a line of a YAML document split into its content and trailing comment.
+// Comment lines and blank lines have no content, while lines of block scalars are content that is never split.
+type layoutLine struct {
+	raw     string
+	indent  int
+	content string
+	comment string
+	block   bool
+}
+
+// preserveLayout carries the comments and blank lines of the previous contents of a config file over to its new contents.
+// Both contents are parsed so that every mapping key and sequence item is matched with the one it was previously
+// written as, even when it was moved, rewritten by a migration or indented differently. Each comment stays with
+// the line that followed it, at the indentation of that line, and is dropped along with it.
+// Top-level keys are kept in their previous order. Contents that cannot be parsed are returned unchanged.
+func preserveLayout(previous, current []byte) []byte {
+	old := splitLines(previous)
+	var order []string
+	for _, line := range old {
+		if key, ok := topLevelKey(line); ok {
+			order = append(order, key)
+		}
+	}
+	lines := reorder(splitLines(current), order)
+
+	var oldDocument, newDocument yaml.Node
+	if err := yaml.Unmarshal(previous, &oldDocument); err != nil {
+		return current
+	}
+	if err := yaml.Unmarshal(joinLines(lines), &newDocument); err != nil {
+		return current
+	}
+	matches := make(map[int][]layoutMatch)
+	if len(oldDocument.Content) > 0 && len(newDocument.Content) > 0 {
+		matchNodes(oldDocument.Content[0], newDocument.Content[0], matches)
+	}
+
+	above := make(map[int][]layoutLine)
+	var head, pending []layoutLine
+	first := true
+	for i, line := range old {
+		if line.content == "" && !line.block {
+			pending = append(pending, line)
+			continue
+		}
+		if line.block {
+			continue
+		}
+		if first {
+			head, pending, first = pending, nil, false
+		}
+		above[i] = pending
+		pending = nil
+	}
+	if first {
+		head, pending = pending, nil
+	}
+
+	var result []string
+	for _, line := range head {
+		result = append(result, line.raw)
+	}
+	for i, line := range lines {
+		raw := line.raw + line.comment
+		matched := matches[i+1]
+		if len(matched) > 0 {
+			previous := matched[0].line - 1
+			for _, comment := range above[previous] {
+				result = append(result, reindent(comment.raw, line.indent-old[previous].indent))
+			}
+			delete(above, previous)
+		}
+		for _, match := range matched {
+			if previous := match.line - 1; match.inline && line.comment == "" && old[previous].comment != "" {
+				raw += old[previous].comment
+				break
+			}
+		}
+		result = append(result, raw)
+	}
+	for _, line := range pending {
+		result = append(result, line.raw)
+	}
+	return []byte(strings.Join(result, "\n") + "\n")
+}
+
+// layoutMatch is a line of the previous document that a node of the current document was written on.
+// Trailing comments belong to the keys and scalars of a line rather than to a mapping item starting on it,
+// since the first key of the item may not be the same anymore.
+type layoutMatch struct {
+	line   int
+	inline bool
+}
+
+// matchNodes records the lines of the previous document that the mapping keys and sequence items of the current node
+// were written on, keyed by the line they are written on now. Keys are matched by name, taking renamed keys into account,
+// and sequence items by their identity.
+func matchNodes(previous, current *yaml.Node, matches map[int][]layoutMatch) {
+	switch {
+	case previous.Kind == yaml.MappingNode && current.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(current.Content); i += 2 {
+			key := current.Content[i]
+			for j := 0; j+1 < len(previous.Content); j += 2 {
+				if normalizeKey(previous.Content[j].Value) == key.Value {
+					matches[key.Line] = append(matches[key.Line], layoutMatch{previous.Content[j].Line, true})
+					matchNodes(previous.Content[j+1], current.Content[i+1], matches)
+					break
+				}
+			}
+		}
+	case previous.Kind == yaml.SequenceNode && current.Kind == yaml.SequenceNode:
+		items := make(map[string][]*yaml.Node)
+		for i, item := range previous.Content {
+			identity := itemIdentity(item, i)
+			items[identity] = append(items[identity], item)
+		}
+		for i, item := range current.Content {
+			identity := itemIdentity(item, i)
+			if len(items[identity]) == 0 {
+				continue
+			}
+			match := items[identity][0]
+			items[identity] = items[identity][1:]
+			matches[item.Line] = append(matches[item.Line], layoutMatch{match.Line, item.Kind != yaml.MappingNode})
+			matchNodes(match, item, matches)
+		}
+	}
+}
+
+// itemIdentity identifies an item of a sequence regardless of how it is written. Scalars and mappings with a name,
+// such as entries and groups, are identified by their name alone, so changing the options of an entry keeps it the same
+// item. Sources are identified by their provider, which defaults to github, their repo and their ref, which was named
+// branch before schema version 2, and outputs by their path and target. Other items are identified by their position.
+func itemIdentity(item *yaml.Node, position int) string {
+	switch item.Kind {
+	case yaml.ScalarNode:
+		return "name=" + item.Value
+	case yaml.MappingNode:
+		if name, ok := mappingValue(item, "name"); ok {
+			return "name=" + name
+		}
+		if repo, ok := mappingValue(item, "repo"); ok {
+			provider, _ := mappingValue(item, "provider")
+			if provider == "" {
+				provider = DefaultProvider
+			}
+			ref, ok := mappingValue(item, "ref")
+			if !ok {
+				ref, _ = mappingValue(item, "branch")
+			}
+			return "source=" + provider + " " + repo + " " + ref
+		}
+		path, hasPath := mappingValue(item, "path")
+		target, hasTarget := mappingValue(item, "target")
+		if hasPath || hasTarget {
+			return "output=" + path + " " + target
+		}
+	}
+	return "position=" + strconv.Itoa(position)
+}
+
+// mappingValue returns the value of a scalar key of a mapping node.
+func mappingValue(mapping *yaml.Node, key string) (string, bool) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key && mapping.Content[i+1].Kind == yaml.ScalarNode {
+			return mapping.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
+// normalizeKey returns the current name of a mapping key.
+func normalizeKey(key string) string {
+	if renamed, ok := keyAliases[key]; ok {
+		return renamed
+	}
+	return key
+}
+
+// reindent shifts a comment line by the difference between the indentation of the line it belongs to now and before.
+func reindent(raw string, shift int) string {
+	trimmed := strings.TrimLeft(raw, " ")
+	indent := len(raw) - len(trimmed) + shift
+	if trimmed == "" || indent < 0 {
+		indent = 0
+	}
+	return strings.Repeat(" ", indent) + trimmed
+}
+
+// joinLines joins the lines of a YAML document back into its contents.
+func joinLines(lines []layoutLine) []byte {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line.raw + line.comment + "\n")
+	}
+	return buf.Bytes()
+}
+
+// reorder moves the top-level keys of the document, along with every line nested under them, into the input order.
+// Keys missing from order stay right after the key they followed.
+func reorder(lines []layoutLine, order []string) []layoutLine {
+	rank := make(map[string]int)
+	for i, key := range order {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+
+	var blocks layoutBlocks
+	for _, line := range lines {
+		if key, ok := topLevelKey(line); ok || len(blocks) == 0 {
+			position := -1
+			if len(blocks) > 0 {
+				position = blocks[len(blocks)-1].rank
+			}
+			if known, found := rank[key]; ok && found {
+				position = known
+			}
+			blocks = append(blocks, layoutBlock{rank: position})
+		}
+		blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, line)
+	}
+	sort.Stable(blocks)
+
+	var reordered []layoutLine
+	for _, block := range blocks {
+		reordered = append(reordered, block.lines...)
+	}
+	return reordered
+}
+
+type layoutBlock struct {
+	rank  int
+	lines []layoutLine
+}
+
+type layoutBlocks []layoutBlock
+
+func (blocks layoutBlocks) Len() int {
+	return len(blocks)
+}
+
+func (blocks layoutBlocks) Less(i, j int) bool {
+	return blocks[i].rank < blocks[j].rank
+}
+
+func (blocks layoutBlocks) Swap(i, j int) {
+	blocks[i], blocks[j] = blocks[j], blocks[i]
+}
+
+// topLevelKey returns the key of a line opening a top-level entry of the document.
+func topLevelKey(line layoutLine) (string, bool) {
+	if line.content == "" || line.block || line.indent > 0 || strings.HasPrefix(line.content, "-") {
+		return "", false
+	}
+	key, _, ok := splitKey(line.content)
+	return key, ok
+}
+
+// splitLines splits a YAML document into lines, separating trailing comments and marking the lines of block scalars.
+func splitLines(data []byte) []layoutLine {
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	var lines []layoutLine
+	block := -1
+	for _, raw := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if raw == "" && len(lines) == 0 && text == "" {
+			break
+		}
+		trimmed := strings.TrimLeft(raw, " ")
+		line := layoutLine{raw: raw, indent: len(raw) - len(trimmed)}
+		if block >= 0 && (trimmed == "" || line.indent > block) {
+			line.content, line.block = trimmed, true
+			lines = append(lines, line)
+			continue
+		}
+		block = -1
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, line)
+			continue
+		}
+
+		line.content, line.comment = splitComment(trimmed)
+		line.raw = raw[:len(raw)-len(line.comment)]
+		rest := strings.TrimLeft(line.content, "- ")
+		if _, value, ok := splitKey(rest); ok && blockIndicator.MatchString(value) {
+			block = line.indent + len(line.content) - len(rest)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitComment separates a trailing comment, along with the whitespace before it, from the content of a line.
+// As in YAML, # only starts a comment after whitespace and outside of quoted scalars.
+func splitComment(text string) (string, string) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			if i == 0 || strings.IndexByte(" [{,", text[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && i > 0 && (text[i-1] == ' ' || text[i-1] == '\t'):
+			content := strings.TrimRight(text[:i], " \t")
+			return content, text[len(content):]
+		}
+	}
+	return text, ""
+}
+
+// splitKey splits the content of a line into a mapping key and its value, which is empty when the key opens a nested node.
+func splitKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "'") || strings.HasPrefix(content, "\"") {
+		return "", "", false
+	}
+	if i := strings.Index(content, ": "); i > 0 {
+		return content[:i], strings.TrimSpace(content[i+2:]), true
+	}
+	if strings.HasSuffix(content, ":") && len(content) > 1 {
+		return content[:len(content)-1], "", true
+	}
+	return "", "", false
+}
@@ -0,0 +1,265 @@
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	rawCommented = `# Shared by every service of the monorepo.
+schema_version: 2
+
+sources:
+# Upstream templates, reviewed quarterly.
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  # Python tooling for the build scripts.
+  - Python
+  - Go  # the services themselves
+  - name: Node
+    # Vendored packages are committed.
+    exclude:
+    - node_modules/
+
+groups:
+- name: Fixtures
+  description: |
+    Scratch data written by the integration suite.
+
+    # Recreated on every run.
+  patterns:
+  - /tmp-fixtures/
+
+# Secrets that must never be committed.
+custom:
+- .env
+- '# not a comment'
+
+# End of spec.
+`
+	rawCommentedSaved = `# Shared by every service of the monorepo.
+schema_version: 2
+
+sources:
+# Upstream templates, reviewed quarterly.
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  - C
+  - Go  # the services themselves
+  - name: Node
+    # Vendored packages are committed.
+    exclude:
+    - node_modules/
+
+groups:
+- name: Fixtures
+  description: |
+    Scratch data written by the integration suite.
+
+    # Recreated on every run.
+  patterns:
+  - /tmp-fixtures/
+
+# Secrets that must never be committed.
+custom:
+- .env
+- '# not a comment'
+- /dist/
+
+# End of spec.
+`
+)
+
+func TestSavePreservesLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignoreit-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".ignoreit.yml")
+	if err = ioutil.WriteFile(filename, []byte(rawCommented), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("Loading the commented config should succeed, got %v instead", err)
+	}
+	source := config.GetSource(DefaultProvider, "github/gitignore", "master")
+	source.RemoveEntry("Python")
+	source.Entries = append(source.Entries, Entry{Name: "C"})
+	config.Custom = append(config.Custom, "/dist/")
+	if err = config.Save(filename); err != nil {
+		t.Fatalf("Saving the commented config should succeed, got %v instead", err)
+	}
+
+	saved, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != rawCommentedSaved {
+		t.Errorf("Saved config should be\n%s\ngot\n%s\ninstead", rawCommentedSaved, saved)
+	}
+}
+
+func TestSavePreservesRewrittenLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		change   func(config *Config)
+		expected string
+	}{
+		{
+			name: "source without provider",
+			previous: `sources:
+# Upstream templates.
+- repo: github/gitignore  # the default provider
+  ref: master
+  entries:
+  - Go
+schema_version: 2
+`,
+			change: func(config *Config) {
+				config.Sources[0].Entries = append(config.Sources[0].Entries, Entry{Name: "C"})
+			},
+			expected: `sources:
+# Upstream templates.
+- provider: github
+  repo: github/gitignore  # the default provider
+  ref: master
+  entries:
+  - C
+  - Go
+custom: []
+schema_version: 2
+`,
+		},
+		{
+			name: "migrated branch",
+			previous: `# Legacy spec.
+sources:
+- repo: github/gitignore
+  branch: master  # stable
+  entries:
+  # Backend.
+  - Go
+custom:
+- .env
+schema_version: 1
+`,
+			change: func(config *Config) {},
+			expected: `# Legacy spec.
+sources:
+- provider: github
+  repo: github/gitignore
+  ref: master  # stable
+  entries:
+  # Backend.
+  - Go
+custom:
+- .env
+schema_version: 2
+`,
+		},
+		{
+			name: "changed entry ref",
+			previous: `sources:
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  # Pinned for the release.
+  - name: Go  # reviewed
+    ref: 0123456789abcdef0123456789abcdef01234567
+schema_version: 2
+`,
+			change: func(config *Config) {
+				config.Sources[0].Entries[0].Ref = "fedcba9876543210fedcba9876543210fedcba98"
+			},
+			expected: `sources:
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  # Pinned for the release.
+  - name: Go  # reviewed
+    ref: fedcba9876543210fedcba9876543210fedcba98
+custom: []
+schema_version: 2
+`,
+		},
+		{
+			name: "re-indented",
+			previous: `sources:
+    # Upstream templates.
+    - provider: github
+      repo: github/gitignore
+      ref: master
+      entries:
+          # The services.
+          - Go
+schema_version: 2
+`,
+			change: func(config *Config) {},
+			expected: `sources:
+# Upstream templates.
+- provider: github
+  repo: github/gitignore
+  ref: master
+  entries:
+  # The services.
+  - Go
+custom: []
+schema_version: 2
+`,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "ignoreit-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".ignoreit.yml")
+
+	for _, test := range tests {
+		if err = ioutil.WriteFile(filename, []byte(test.previous), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := LoadConfig(filename)
+		if err != nil {
+			t.Fatalf("Loading the %s config should succeed, got %v instead", test.name, err)
+		}
+		test.change(&config)
+		if err = config.Save(filename); err != nil {
+			t.Fatalf("Saving the %s config should succeed, got %v instead", test.name, err)
+		}
+
+		saved, _ := ioutil.ReadFile(filename)
+		if string(saved) != test.expected {
+			t.Errorf("Saved %s config should be\n%s\ngot\n%s\ninstead", test.name, test.expected, saved)
+		}
+	}
+}
+
+func TestSplitComment(t *testing.T) {
+	tests := []struct{ text, content, comment string }{
+		{"- Go  # the services", "- Go", "  # the services"},
+		{"- '# not a comment'", "- '# not a comment'", ""},
+		{"- foo#bar", "- foo#bar", ""},
+		{`- "a # b" # c`, `- "a # b"`, " # c"},
+		{"- don't # c", "- don't", " # c"},
+	}
+	for _, test := range tests {
+		content, comment := splitComment(test.text)
+		if content != test.content || comment != test.comment {
+			t.Errorf("Line %q should split into %q and %q, got %q and %q instead", test.text, test.content, test.comment, content, comment)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package spec
+
+import (
+	"fmt"
+)
+
+// migrations upgrade a raw config document by a single schema version, keyed by the version they upgrade from.
+// Every schema version below schemaVersion must have a migration so that any older file can be loaded.
+var migrations = map[uint]func(document map[interface{}]interface{}) error{
+	1: migrateV1,
+}
+
+// Migrate rewrites the config file at the tool's schema version and returns the version it was written with.
+// Files that are already at the tool's schema version are left untouched.
+func Migrate(configFilename string) (uint, error) {
+	config, version, err := loadConfig(configFilename)
+	if err != nil || version == schemaVersion {
+		return version, err
+	}
+
+	return version, config.Save(configFilename)
+}
+
+// documentVersion reads the schema version of a raw config document.
+// Schema version 1 files did not always record their version, so a document that doesn't specify one is assumed
+// to be at version 1 unless it is empty, in which case it is at the tool's schema version.
+func documentVersion(document map[interface{}]interface{}) (uint, error) {
+	raw, ok := document["schema_version"]
+	if !ok || raw == nil {
+		if len(document) == 0 {
+			return schemaVersion, nil
+		}
+		return 1, nil
+	}
+
+	version, ok := raw.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("schema_version %v is not a valid version", raw)
+	}
+	return uint(version), nil
+}
+
+// migrate upgrades a raw config document from version to schemaVersion in memory.
+func migrate(document map[interface{}]interface{}, version uint) error {
+	for ; version < schemaVersion; version++ {
+		if err := migrations[version](document); err != nil {
+			return fmt.Errorf("error migrating schema version %d to %d: %s", version, version+1, err)
+		}
+	}
+	document["schema_version"] = schemaVersion
+	return nil
+}
+
+// migrateV1 renames the branch of every source to ref and makes the implicit github provider explicit.
+func migrateV1(document map[interface{}]interface{}) error {
+	sources, ok := document["sources"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, raw := range sources {
+		source, ok := raw.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("source %v is not a mapping", raw)
+		}
+		branch, ok := source["branch"]
+		if !ok || branch == nil {
+			return fmt.Errorf("source %v has no branch", source["repo"])
+		}
+		source["provider"] = DefaultProvider
+		source["ref"] = branch
+		delete(source, "branch")
+	}
+	return nil
+}
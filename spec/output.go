@@ -0,0 +1,35 @@
+package spec
+
+import (
+	"fmt"
+)
+
+const (
+	// TargetInfoExclude is the per-clone .git/info/exclude file, which is never committed.
+	TargetInfoExclude = "info-exclude"
+	// TargetGlobalExcludes is the user's global excludes file configured by core.excludesFile.
+	TargetGlobalExcludes = "global-excludes"
+)
+
+// Output is an ignore file generated from the config.
+// Path is relative to the directory of the config file.
+// Target names a well known git exclude file to write instead of Path, either info-exclude or global-excludes.
+// Format names the syntax of the ignore file, such as gitignore or dockerignore, and is inferred from Path when empty.
+type Output struct {
+	Path   string `yaml:"path,omitempty"`
+	Target string `yaml:"target,omitempty"`
+	Format string `yaml:"format,omitempty"`
+}
+
+// Validate checks that the output names exactly one of a path or a known target.
+func (output Output) Validate() error {
+	switch {
+	case output.Path == "" && output.Target == "":
+		return fmt.Errorf("output must specify either a path or a target")
+	case output.Path != "" && output.Target != "":
+		return fmt.Errorf("output cannot specify both path %s and target %s", output.Path, output.Target)
+	case output.Target != "" && output.Target != TargetInfoExclude && output.Target != TargetGlobalExcludes:
+		return fmt.Errorf("unknown output target %q, expected %s or %s", output.Target, TargetInfoExclude, TargetGlobalExcludes)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/whoshuu/ignoreit/network"
+)
+
+// DefaultProvider is the provider assumed for sources that don't name one.
+const DefaultProvider = "github"
+
+// gitignoreSuffix is the extension of every template file, which is dropped from entry names.
+const gitignoreSuffix = ".gitignore"
+
+// gitlabPageSize is the number of tree items requested per page from the GitLab API, which is its maximum.
+const gitlabPageSize = 100
+
+// provider describes how to download raw files from the repositories of a git hosting service,
+// how to list the files of a repository at a ref, and how to resolve a ref to the commit it points at.
+type provider struct {
+	rawFormat    string
+	commitFormat string
+	list         func(repo, ref string) ([]string, error)
+}
+
+var providers = map[string]provider{
+	"github": {
+		rawFormat:    "https://raw.githubusercontent.com/%s/%s/%s.gitignore",
+		commitFormat: "https://api.github.com/repos/%s/commits/%s",
+		list:         listGitHub,
+	},
+	"gitlab": {
+		rawFormat:    "https://gitlab.com/%s/-/raw/%s/%s.gitignore",
+		commitFormat: "https://gitlab.com/api/v4/projects/%s/repository/commits/%s",
+		list:         listGitLab,
+	},
+}
+
+func (p provider) rawURL(repo, ref, entry string) string {
+	return fmt.Sprintf(p.rawFormat, repo, ref, entry)
+}
+
+// resolve returns the full hash of the commit that ref points at in the repository.
+// Both GitHub and GitLab describe the commit as a JSON object, with its hash in the sha or id field respectively.
+func (p provider) resolve(repo, ref string) (string, error) {
+	if IsCommit(ref) {
+		return ref, nil
+	}
+
+	project := repo
+	if strings.Contains(p.commitFormat, "/projects/") {
+		project = url.QueryEscape(repo)
+	}
+	body, err := network.Fetch(fmt.Sprintf(p.commitFormat, project, url.QueryEscape(ref)))
+	if err != nil {
+		return "", err
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+		ID  string `json:"id"`
+	}
+	if err = json.Unmarshal(body, &commit); err != nil {
+		return "", fmt.Errorf("error parsing commit %s of %s: %s", ref, repo, err)
+	}
+	if hash := commit.SHA + commit.ID; IsCommit(hash) {
+		return hash, nil
+	}
+	return "", fmt.Errorf("no commit found for %s of %s", ref, repo)
+}
+
+// IsCommit reports whether ref is a full commit hash rather than a branch or tag that can move.
+func IsCommit(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Providers returns the names of every supported provider in sorted order.
+func Providers() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkProvider(name string) error {
+	if _, ok := providers[name]; !ok {
+		return fmt.Errorf("unknown provider %q, expected one of: %s", name, strings.Join(Providers(), ", "))
+	}
+	return nil
+}
+
+// treeItem is a file or directory in the repository tree listings of both GitHub and GitLab.
+type treeItem struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// listGitHub lists the files of a repository with a single recursive request to the GitHub trees API.
+func listGitHub(repo, ref string) ([]string, error) {
+	body, err := network.Fetch(fmt.Sprintf("https://api.github.com/repos/%s/git/trees/%s?recursive=1", repo, url.QueryEscape(ref)))
+	if err != nil {
+		return nil, err
+	}
+
+	var tree struct {
+		Tree      []treeItem `json:"tree"`
+		Truncated bool       `json:"truncated"`
+	}
+	if err = json.Unmarshal(body, &tree); err != nil {
+		return nil, fmt.Errorf("error parsing tree of %s: %s", repo, err)
+	}
+	if tree.Truncated {
+		return nil, fmt.Errorf("tree of %s is too large to be listed", repo)
+	}
+	return blobPaths(tree.Tree), nil
+}
+
+// listGitLab lists the files of a repository from the GitLab repository tree API, one page at a time.
+func listGitLab(repo, ref string) ([]string, error) {
+	var paths []string
+	for page := 1; ; page++ {
+		body, err := network.Fetch(fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/repository/tree?ref=%s&recursive=true&per_page=%d&page=%d",
+			url.QueryEscape(repo), url.QueryEscape(ref), gitlabPageSize, page))
+		if err != nil {
+			return nil, err
+		}
+
+		var items []treeItem
+		if err = json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("error parsing tree of %s: %s", repo, err)
+		}
+		paths = append(paths, blobPaths(items)...)
+		if len(items) < gitlabPageSize {
+			return paths, nil
+		}
+	}
+}
+
+func blobPaths(items []treeItem) []string {
+	var paths []string
+	for _, item := range items {
+		if item.Type == "blob" {
+			paths = append(paths, item.Path)
+		}
+	}
+	return paths
+}
+
+// entryNames converts the file paths of a repository into the sorted names of its .gitignore entries.
+func entryNames(paths []string) []string {
+	var names []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, gitignoreSuffix) && path != gitignoreSuffix && !strings.HasSuffix(path, "/"+gitignoreSuffix) {
+			names = append(names, strings.TrimSuffix(path, gitignoreSuffix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
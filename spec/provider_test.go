@@ -0,0 +1,17 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntryNames(t *testing.T) {
+	paths := []string{"README.md", "Go.gitignore", ".gitignore", "Global/macOS.gitignore", "community/.gitignore", "C++.gitignore"}
+
+	names := entryNames(paths)
+
+	expected := []string{"C++", "Global/macOS", "Go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Entry names should be %v, got %v instead", expected, names)
+	}
+}
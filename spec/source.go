@@ -1,18 +1,23 @@
 package spec
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/whoshuu/ignoreit/network"
 )
 
 // Source represents a collection of .gitignore resources.
-// Repo and Branch uniquely identify a remote repository of .gitignore files.
+// Provider, Repo and Ref uniquely identify a remote repository of .gitignore files and the git ref to read them at.
 // Entries is a list of files to sync with, exluding the .gitignore suffix. Ex: Go is a valid entry.
+// Omit lists entries inherited from included specs that should be dropped from this source.
 type Source struct {
-	Repo    string   `yaml:"repo"`
-	Branch  string   `yaml:"branch"`
-	Entries []string `yaml:"entries"`
+	Provider string   `yaml:"provider"`
+	Repo     string   `yaml:"repo"`
+	Ref      string   `yaml:"ref"`
+	Entries  Entries  `yaml:"entries"`
+	Omit     []string `yaml:"omit,omitempty"`
 }
 
 // Sources is a collection of Source structs
@@ -23,8 +28,11 @@ func (sources Sources) Len() int {
 }
 
 func (sources Sources) Less(i, j int) bool {
+	if sources[i].Provider != sources[j].Provider {
+		return sources[i].Provider < sources[j].Provider
+	}
 	if sources[i].Repo == sources[j].Repo {
-		return sources[i].Branch < sources[j].Branch
+		return sources[i].Ref < sources[j].Ref
 	}
 
 	return sources[i].Repo < sources[j].Repo
@@ -34,22 +42,154 @@ func (sources Sources) Swap(i, j int) {
 	sources[i], sources[j] = sources[j], sources[i]
 }
 
+// String describes the source as its repo and ref, prefixed by the provider unless it is the default.
+func (source Source) String() string {
+	if source.Provider == DefaultProvider {
+		return source.Repo + " - " + source.Ref
+	}
+	return source.Provider + ":" + source.Repo + " - " + source.Ref
+}
+
+// ParseReference splits a reference to a source, in the form [provider:]repo[@ref], into its parts.
+// The default provider is assumed when the reference doesn't name one, while a missing ref is left empty.
+func ParseReference(reference string) (provider, repo, ref string) {
+	provider = DefaultProvider
+	if i := strings.Index(reference, ":"); i >= 0 {
+		provider, reference = reference[:i], reference[i+1:]
+	}
+	if i := strings.LastIndex(reference, "@"); i >= 0 {
+		reference, ref = reference[:i], reference[i+1:]
+	}
+	return provider, reference, ref
+}
+
+// Matches reports whether the source is identified by reference, in the form [provider:]repo[@ref].
+// Parts of the reference that are omitted match any provider or ref.
+func (source Source) Matches(reference string) bool {
+	if i := strings.Index(reference, ":"); i >= 0 {
+		if reference[:i] != source.Provider {
+			return false
+		}
+		reference = reference[i+1:]
+	}
+	if i := strings.LastIndex(reference, "@"); i >= 0 {
+		if reference[i+1:] != source.Ref {
+			return false
+		}
+		reference = reference[:i]
+	}
+	return reference == source.Repo
+}
+
 // GetDownloadLink returns the link to download a raw form of the entry from the source.
-func (source Source) GetDownloadLink(entry string) string {
-	return "https://raw.githubusercontent.com/" + source.Repo + "/" + source.Branch + "/" + entry + ".gitignore"
+// The ref of the entry takes precedence over the ref of the source when it is set.
+func (source Source) GetDownloadLink(entry Entry) string {
+	ref := source.Ref
+	if entry.Ref != "" {
+		ref = entry.Ref
+	}
+	return providers[source.Provider].rawURL(source.Repo, ref, entry.Name)
+}
+
+// ListEntries returns the sorted names of every .gitignore entry available from the source, using the listing API of its provider.
+// Files named just .gitignore, like the one ignoring files of the repository itself, are not entries.
+func (source Source) ListEntries() ([]string, error) {
+	paths, err := providers[source.Provider].list(source.Repo, source.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("error listing entries of %s: %s", source, err)
+	}
+	return entryNames(paths), nil
+}
+
+// ResolveRef returns the commit that ref currently points at in the repository of the source, using the API of its provider.
+// Refs that are already full commit hashes are returned as is, without a request.
+func (source Source) ResolveRef(ref string) (string, error) {
+	commit, err := providers[source.Provider].resolve(source.Repo, ref)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s of %s: %s", ref, source, err)
+	}
+	return commit, nil
+}
+
+// IndexOf returns the position of the entry with the input name in source.Entries, or -1 if it doesn't exist.
+func (source Source) IndexOf(name string) int {
+	for i, entry := range source.Entries {
+		if entry.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// InsertEntry inserts the entry into source.Entries at index, or appends it when index is out of range.
+func (source *Source) InsertEntry(entry Entry, index int) {
+	if index < 0 || index >= len(source.Entries) {
+		source.Entries = append(source.Entries, entry)
+		return
+	}
+	source.Entries = append(source.Entries[:index], append(Entries{entry}, source.Entries[index:]...)...)
+}
+
+// MoveEntry removes the entry named name from the source from and inserts it into the source to as newName,
+// keeping its options. The entry is placed right before the entry named before or right after the entry named after.
+// When neither is given, it keeps its position if it stays in the same source, and is appended to to.Entries otherwise.
+func MoveEntry(from, to *Source, name, newName, before, after string) error {
+	index := from.IndexOf(name)
+	if index < 0 {
+		return fmt.Errorf("%s is not an entry of %s", name, from)
+	}
+	if before != "" && after != "" {
+		return fmt.Errorf("cannot place %s both before %s and after %s", newName, before, after)
+	}
+	for _, anchor := range []string{before, after} {
+		if anchor == "" {
+			continue
+		}
+		if anchor == name && from == to {
+			return fmt.Errorf("cannot place %s relative to itself", newName)
+		}
+		if to.IndexOf(anchor) < 0 {
+			return fmt.Errorf("%s is not an entry of %s", anchor, to)
+		}
+	}
+	if existing := to.IndexOf(newName); existing >= 0 && (from != to || existing != index) {
+		return fmt.Errorf("%s is already an entry of %s", newName, to)
+	}
+
+	entry := from.Entries[index]
+	entry.Name = newName
+	from.Entries = append(from.Entries[:index], from.Entries[index+1:]...)
+	switch {
+	case before != "":
+		index = to.IndexOf(before)
+	case after != "":
+		index = to.IndexOf(after) + 1
+	case from != to:
+		index = len(to.Entries)
+	}
+	to.InsertEntry(entry, index)
+	return nil
+}
+
+// GetEntry grabs a modifiable reference to the entry with the input name, or nil if it doesn't exist.
+func (source *Source) GetEntry(name string) *Entry {
+	for i := range source.Entries {
+		if source.Entries[i].Name == name {
+			return &source.Entries[i]
+		}
+	}
+	return nil
 }
 
 // AddEntry adds the entry to the source.Entries slice.
 // If the entry already exists, nothing is modified and this method returns early.
 func (source *Source) AddEntry(entry string) error {
-	for _, existingEntry := range source.Entries {
-		if existingEntry == entry {
-			return nil
-		}
+	if source.GetEntry(entry) != nil {
+		return nil
 	}
 
-	if network.EntryExists(source.GetDownloadLink(entry)) {
-		source.Entries = append(source.Entries, entry)
+	if network.EntryExists(source.GetDownloadLink(Entry{Name: entry})) {
+		source.Entries = append(source.Entries, Entry{Name: entry})
 	}
 
 	return nil
@@ -60,7 +200,7 @@ func (source *Source) AddEntry(entry string) error {
 // Calling this multiple times in a row may be inefficient.
 func (source *Source) RemoveEntry(entry string) error {
 	for i, existingEntry := range source.Entries {
-		if existingEntry == entry {
+		if existingEntry.Name == entry {
 			source.Entries = append(source.Entries[:i], source.Entries[i+1:]...)
 			break
 		}
@@ -69,16 +209,23 @@ func (source *Source) RemoveEntry(entry string) error {
 	return nil
 }
 
-// Clean sorts and dedupes source.Entries, where deduping is the equivalent of removing an entry.
-// The resulting source.Entries should be a tightly packed, sorted, and unique slice of strings.
+// Clean sorts and dedupes source.Entries by name, where deduping is the equivalent of removing an entry.
+// The resulting source.Entries should be a tightly packed, sorted, and unique slice of entries.
 func (source *Source) Clean() error {
-	sort.Strings(source.Entries)
-	for i := 0; i < len(source.Entries)-1; {
-		if source.Entries[i] == source.Entries[i+1] {
-			source.Entries = append(source.Entries[:i], source.Entries[i+1:]...)
-		} else {
-			i++
+	sort.Stable(source.Entries)
+	return source.Dedupe()
+}
+
+// Dedupe removes every entry whose name appears earlier in source.Entries, without changing their order.
+func (source *Source) Dedupe() error {
+	seen := make(map[string]bool)
+	entries := source.Entries[:0]
+	for _, entry := range source.Entries {
+		if !seen[entry.Name] {
+			seen[entry.Name] = true
+			entries = append(entries, entry)
 		}
 	}
+	source.Entries = entries
 	return nil
 }
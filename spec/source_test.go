@@ -0,0 +1,132 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func entryNamesOf(source Source) []string {
+	names := []string{}
+	for _, entry := range source.Entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func newSource(names ...string) *Source {
+	source := &Source{Provider: DefaultProvider, Repo: "github/gitignore", Ref: "master"}
+	for _, name := range names {
+		source.Entries = append(source.Entries, Entry{Name: name})
+	}
+	return source
+}
+
+func TestDedupe(t *testing.T) {
+	source := newSource("Node", "Go", "Node", "C", "Go")
+	source.Dedupe()
+
+	expected := []string{"Node", "Go", "C"}
+	if names := entryNamesOf(*source); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Deduped entries should be %v, got %v instead", expected, names)
+	}
+}
+
+func TestCleanPreservesOrder(t *testing.T) {
+	config := Config{
+		Order:   OrderPreserve,
+		Sources: Sources{*newSource("Node", "Go", "Node"), {Provider: DefaultProvider, Repo: "a/b", Ref: "master", Entries: Entries{{Name: "X"}}}},
+	}
+	config.clean()
+
+	if config.Sources[0].Repo != "github/gitignore" {
+		t.Errorf("First source should be github/gitignore, got %s instead", config.Sources[0].Repo)
+	}
+	expected := []string{"Node", "Go"}
+	if names := entryNamesOf(config.Sources[0]); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Preserved entries should be %v, got %v instead", expected, names)
+	}
+
+	config.Order = ""
+	config.clean()
+	if config.Sources[0].Repo != "a/b" {
+		t.Errorf("First sorted source should be a/b, got %s instead", config.Sources[0].Repo)
+	}
+	expected = []string{"Go", "Node"}
+	if names := entryNamesOf(config.Sources[1]); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Sorted entries should be %v, got %v instead", expected, names)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	if _, _, err := parseConfig([]byte("order: preserve\nschema_version: 2\n")); err != nil {
+		t.Errorf("Order preserve should be valid, got %v instead", err)
+	}
+	if _, _, err := parseConfig([]byte("order: random\nschema_version: 2\n")); err == nil {
+		t.Errorf("Order random should be rejected, got no error instead")
+	}
+}
+
+func TestMoveEntry(t *testing.T) {
+	tests := []struct {
+		name, newName, before, after string
+		from, to                     []string
+	}{
+		{"Go", "Golang", "", "", []string{"C", "Golang", "Node"}, nil},
+		{"Node", "Node", "C", "", []string{"Node", "C", "Go"}, nil},
+		{"C", "C", "", "Node", []string{"Go", "Node", "C"}, nil},
+		{"Go", "community/Go", "", "", []string{"C", "Node"}, []string{"Rust", "community/Go"}},
+		{"Go", "Go", "Rust", "", []string{"C", "Node"}, []string{"Go", "Rust"}},
+	}
+	for _, test := range tests {
+		from := newSource("C", "Go", "Node")
+		to := from
+		if test.to != nil {
+			to = newSource("Rust")
+		}
+		if err := MoveEntry(from, to, test.name, test.newName, test.before, test.after); err != nil {
+			t.Errorf("Moving %s should succeed, got %v instead", test.name, err)
+			continue
+		}
+		if names := entryNamesOf(*from); !reflect.DeepEqual(names, test.from) {
+			t.Errorf("Entries after moving %s should be %v, got %v instead", test.name, test.from, names)
+		}
+		if test.to != nil {
+			if names := entryNamesOf(*to); !reflect.DeepEqual(names, test.to) {
+				t.Errorf("Destination entries after moving %s should be %v, got %v instead", test.name, test.to, names)
+			}
+		}
+	}
+}
+
+func TestMoveEntryErrors(t *testing.T) {
+	source := newSource("C", "Go", "Node")
+	for _, args := range [][4]string{
+		{"Rust", "Rust", "", ""},
+		{"Go", "Node", "", ""},
+		{"Go", "Go", "Rust", ""},
+		{"Go", "Go", "Go", ""},
+		{"Go", "Go", "C", "Node"},
+	} {
+		if err := MoveEntry(source, source, args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("Moving with %v should fail, got no error instead", args)
+		}
+	}
+	expected := []string{"C", "Go", "Node"}
+	if names := entryNamesOf(*source); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Entries after failed moves should be %v, got %v instead", expected, names)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct{ reference, provider, repo, ref string }{
+		{"github/gitignore", DefaultProvider, "github/gitignore", ""},
+		{"gitlab:group/templates@v1", "gitlab", "group/templates", "v1"},
+		{"github/gitignore@main", DefaultProvider, "github/gitignore", "main"},
+	}
+	for _, test := range tests {
+		provider, repo, ref := ParseReference(test.reference)
+		if provider != test.provider || repo != test.repo || ref != test.ref {
+			t.Errorf("Reference %s should parse to %s %s %s, got %s %s %s instead", test.reference, test.provider, test.repo, test.ref, provider, repo, ref)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package walk
+
+// skippedDirs hold version control data or third party dependencies, whose files belong to other projects
+// and say nothing about the project being walked.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// Skip reports whether a directory named name is left out when walking a project tree.
+// The root of the walk is never skipped, whatever its name.
+func Skip(name string) bool {
+	return skippedDirs[name]
+}